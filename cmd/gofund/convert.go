@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ops2go/go-fundamentals/encode"
+)
+
+const convertUsage = `usage: gofund convert [flags] [< input] [> output]
+
+Convert reads records in one format and writes them in another, one record
+at a time, from standard input to standard output unless -in or -out is
+given. Formats default to the extensions of -in and -out.
+
+Without -schema, records are read as field maps and the binary format
+cannot be used. -fields keeps only the named keys; a record that lacks
+one is written without it, or with an empty CSV field. CSV output has its
+columns in the order of -fields, or else the sorted keys of the first
+record. Records are converted one at a time, so without -fields a later
+record with a key the first one lacks is an error.
+
+A schema is a comma-separated list of name[:kind] that fixes the fields,
+their order and types:
+
+  string (default), bool, int8 ... int64, uint8 ... uint64,
+  varint, uvarint, float32, float64
+
+for example -schema id:uint32,name,score:float64. With -schema, -fields
+picks the schema fields to write, in the order given.
+
+Flags:
+`
+
+// convertConfig holds the flags of the convert command.
+type convertConfig struct {
+	from, to  string
+	in, out   string
+	fields    string
+	schema    string
+	noHeader  bool
+	outHeader bool
+	pretty    bool
+}
+
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var cfg convertConfig
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, convertUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.from, "from", "", "input `format`: json, ndjson, csv or binary")
+	fs.StringVar(&cfg.to, "to", "", "output `format`: json, ndjson, csv or binary")
+	fs.StringVar(&cfg.in, "in", "", "read from `file` instead of standard input")
+	fs.StringVar(&cfg.out, "out", "", "write to `file` instead of standard output")
+	fs.StringVar(&cfg.fields, "fields", "", "comma-separated `names` of the fields to keep")
+	fs.StringVar(&cfg.schema, "schema", "", "record `schema`, as described above")
+	fs.BoolVar(&cfg.noHeader, "noheader", false, "CSV input has no header; columns are named by -schema or -fields")
+	fs.BoolVar(&cfg.outHeader, "header", true, "write a header line in CSV output")
+	fs.BoolVar(&cfg.pretty, "pretty", false, "indent JSON output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	from, err := pickCodec(cfg.from, cfg.in, "input")
+	if err != nil {
+		return err
+	}
+	to, err := pickCodec(cfg.to, cfg.out, "output")
+	if err != nil {
+		return err
+	}
+	schema, err := parseSchema(cfg.schema)
+	if err != nil {
+		return err
+	}
+	keep := splitList(cfg.fields)
+	columns := keep
+	var newRecord func() any
+	var project func(any) any
+	if schema != nil {
+		newRecord = newStruct(structType(schema))
+		columns = schemaNames(schema)
+		if keep != nil {
+			if project, err = projection(schema, keep); err != nil {
+				return err
+			}
+		}
+	}
+	if newRecord == nil && (from == encode.Binary || to == encode.Binary) {
+		return errors.New("the binary format needs -schema")
+	}
+
+	if cfg.in != "" {
+		f, err := os.Open(cfg.in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdin = f
+	}
+	if cfg.noHeader {
+		if from != encode.CSV {
+			return errors.New("-noheader applies only to CSV input")
+		}
+		if len(columns) == 0 {
+			return errors.New("-noheader needs -schema or -fields to name the columns")
+		}
+		header, err := csvHeader(columns)
+		if err != nil {
+			return err
+		}
+		stdin = io.MultiReader(strings.NewReader(header), stdin)
+	}
+	var outFile *os.File
+	if cfg.out != "" {
+		if outFile, err = os.Create(cfg.out); err != nil {
+			return err
+		}
+		stdout = outFile
+	}
+	bw := bufio.NewWriter(stdout)
+
+	dec := from.NewDecoder(stdin)
+	if d, ok := dec.(interface{ UseNumber() }); ok {
+		// Keep numbers exactly as written when records are maps.
+		d.UseNumber()
+	}
+	enc := to.NewEncoder(bw)
+	if e, ok := enc.(interface{ SetIndent(prefix, indent string) }); ok && cfg.pretty {
+		e.SetIndent("", "  ")
+	}
+	if e, ok := enc.(interface{ OmitHeader() }); ok && !cfg.outHeader {
+		e.OmitHeader()
+	}
+	if e, ok := enc.(interface{ SetHeader(header []string) }); ok && schema == nil && keep != nil {
+		e.SetHeader(keep)
+	}
+	_, err = encode.Convert(selectFields{enc, keep, project}, dec, newRecord)
+	if cerr := enc.Close(); err == nil {
+		err = cerr
+	}
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	if outFile != nil {
+		// A failed Close can mean the output was not fully written.
+		if cerr := outFile.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// csvHeader returns columns as a CSV header line.
+func csvHeader(columns []string) (string, error) {
+	var b strings.Builder
+	err := encode.NewCSVWriter(&b).WriteAll([][]string{columns})
+	return b.String(), err
+}
+
+// pickCodec returns the codec named by format, or else the codec for the
+// extension of file.
+func pickCodec(format, file, what string) (encode.Codec, error) {
+	if format != "" {
+		c, ok := encode.Lookup(format)
+		if !ok {
+			return nil, fmt.Errorf("unknown %s format %q", what, format)
+		}
+		return c, nil
+	}
+	if file != "" {
+		if c, ok := encode.ForFile(file); ok {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%s format not given and not known from the file name", what)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	list := strings.Split(s, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// schemaKinds maps schema kinds to Go types and binary kinds.
+var schemaKinds = map[string]struct {
+	typ reflect.Type
+	bin string
+}{
+	"string":  {reflect.TypeFor[string](), "string"},
+	"bool":    {reflect.TypeFor[bool](), "bool"},
+	"int8":    {reflect.TypeFor[int8](), "i8"},
+	"int16":   {reflect.TypeFor[int16](), "i16"},
+	"int32":   {reflect.TypeFor[int32](), "i32"},
+	"int64":   {reflect.TypeFor[int64](), "i64"},
+	"uint8":   {reflect.TypeFor[uint8](), "u8"},
+	"uint16":  {reflect.TypeFor[uint16](), "u16"},
+	"uint32":  {reflect.TypeFor[uint32](), "u32"},
+	"uint64":  {reflect.TypeFor[uint64](), "u64"},
+	"varint":  {reflect.TypeFor[int64](), "varint"},
+	"uvarint": {reflect.TypeFor[uint64](), "uvarint"},
+	"float32": {reflect.TypeFor[float32](), "f32"},
+	"float64": {reflect.TypeFor[float64](), "f64"},
+}
+
+// A schemaField is one name[:kind] entry of a schema.
+type schemaField struct {
+	name string
+	kind string
+}
+
+// parseSchema parses a schema. It returns nil for an empty schema.
+func parseSchema(schema string) ([]schemaField, error) {
+	var fields []schemaField
+	for _, spec := range splitList(schema) {
+		name, kind, _ := strings.Cut(spec, ":")
+		if kind == "" {
+			kind = "string"
+		}
+		if _, ok := schemaKinds[kind]; name == "" || !ok {
+			return nil, fmt.Errorf("invalid schema field %q", spec)
+		}
+		if strings.ContainsAny(name, "\",`") {
+			return nil, fmt.Errorf("invalid schema field name %q", name)
+		}
+		fields = append(fields, schemaField{name, kind})
+	}
+	return fields, nil
+}
+
+func schemaNames(fields []schemaField) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// structType returns a struct type with a field for each schema field,
+// tagged with its name for every format.
+func structType(fields []schemaField) reflect.Type {
+	sf := make([]reflect.StructField, len(fields))
+	for i, f := range fields {
+		k := schemaKinds[f.kind]
+		sf[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: k.typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:%q csv:%q bin:%q`, f.name, f.name, k.bin)),
+		}
+	}
+	return reflect.StructOf(sf)
+}
+
+// newStruct returns a constructor for pointers to new values of t.
+func newStruct(t reflect.Type) func() any {
+	return func() any { return reflect.New(t).Interface() }
+}
+
+// projection returns a function that copies a record of the schema's
+// struct type into a new struct holding only the fields in keep, in that
+// order.
+func projection(schema []schemaField, keep []string) (func(any) any, error) {
+	fields := make([]schemaField, len(keep))
+	index := make([]int, len(keep))
+	for i, name := range keep {
+		j := slices.IndexFunc(schema, func(f schemaField) bool { return f.name == name })
+		if j < 0 {
+			return nil, fmt.Errorf("-fields names %q, which is not in -schema", name)
+		}
+		fields[i], index[i] = schema[j], j
+	}
+	t := structType(fields)
+	return func(v any) any {
+		src := reflect.ValueOf(v).Elem()
+		dst := reflect.New(t)
+		for i, j := range index {
+			dst.Elem().Field(i).Set(src.Field(j))
+		}
+		return dst.Interface()
+	}, nil
+}
+
+// selectFields is an Encoder that drops the fields that are not selected.
+// Schema records are copied by project, if set, into a struct holding only
+// the selected fields; map records keep just the keys in keep, unless keep
+// is empty.
+type selectFields struct {
+	encode.Encoder
+	keep    []string
+	project func(any) any
+}
+
+func (s selectFields) Encode(v any) error {
+	if s.project != nil {
+		v = s.project(v)
+	} else if m, ok := v.(*map[string]any); ok && len(s.keep) > 0 {
+		sel := make(map[string]any, len(s.keep))
+		for _, k := range s.keep {
+			if x, ok := (*m)[k]; ok {
+				sel[k] = x
+			}
+		}
+		v = sel
+	}
+	return s.Encoder.Encode(v)
+}
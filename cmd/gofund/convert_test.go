@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// convertTests run gofund convert on a file in testdata and compare the
+// output with testdata/<name>.golden.
+var convertTests = []struct {
+	name  string
+	input string
+	args  []string
+}{
+	{"json_to_csv", "people.json", []string{"-from", "json", "-to", "csv"}},
+	{"json_to_csv_fields", "people.json", []string{"-from", "json", "-to", "csv", "-fields", "name,id"}},
+	{"json_to_csv_noheader", "people.json", []string{"-from", "json", "-to", "csv", "-fields", "note,name", "-header=false"}},
+	{"json_to_ndjson_schema", "people.json", []string{"-from", "json", "-to", "ndjson", "-schema", "id:uint32,name"}},
+	{"csv_to_ndjson", "people.csv", []string{"-from", "csv", "-to", "ndjson"}},
+	{"csv_to_json_pretty", "people.csv", []string{"-from", "csv", "-to", "json", "-pretty"}},
+	{"csv_schema_to_csv_noheader", "people.csv", []string{"-from", "csv", "-to", "csv", "-schema", "id:uint32,note", "-header=false"}},
+	{"csv_noheader_to_ndjson", "people_noheader.csv", []string{"-from", "csv", "-to", "ndjson", "-noheader", "-fields", "id,name,note"}},
+	{"csv_noheader_quoted_names", "pair.csv", []string{"-from", "csv", "-to", "csv", "-noheader", "-fields", `say "hi",n`}},
+	{"json_to_csv_schema_fields", "people.json", []string{"-from", "json", "-to", "csv", "-schema", "id:uint32,name,score:float64", "-fields", "score,id"}},
+	{"csv_noheader_schema_fields", "people_noheader.csv", []string{"-from", "csv", "-to", "ndjson", "-noheader", "-schema", "id:int64,name,note", "-fields", "note,id"}},
+	{"sparse_to_csv_fields", "sparse.json", []string{"-from", "json", "-to", "csv", "-fields", "id,name,email"}},
+	{"sparse_to_ndjson_fields", "sparse.json", []string{"-from", "json", "-to", "ndjson", "-fields", "id,email"}},
+}
+
+func TestConvertGolden(t *testing.T) {
+	for _, tt := range convertTests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := os.ReadFile(filepath.Join("testdata", tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"convert"}, tt.args...)
+			if code := run(args, bytes.NewReader(in), &stdout, &stderr); code != 0 {
+				t.Fatalf("exit status %d: %s", code, stderr.String())
+			}
+			checkGolden(t, tt.name, stdout.Bytes())
+		})
+	}
+}
+
+func TestConvertFiles(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "people.csv")
+	args := []string{"convert", "-in", filepath.Join("testdata", "people.json"), "-out", out}
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("wrote %q to standard output", stdout.String())
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "json_to_csv", got)
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"binary without schema", []string{"-from", "json", "-to", "binary"}, 1},
+		{"noheader without columns", []string{"-from", "csv", "-to", "json", "-noheader"}, 1},
+		{"noheader for json", []string{"-from", "json", "-to", "csv", "-noheader", "-fields", "a"}, 1},
+		{"unknown format", []string{"-from", "yaml", "-to", "json"}, 1},
+		{"fields not in schema", []string{"-from", "json", "-to", "csv", "-schema", "id:int32,name", "-fields", "id,email"}, 1},
+		{"extra arguments", []string{"-from", "json", "-to", "csv", "x"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"convert"}, tt.args...)
+			if code := run(args, bytes.NewReader(nil), &stdout, &stderr); code != tt.code {
+				t.Fatalf("exit status %d, want %d: %s", code, tt.code, stderr.String())
+			}
+		})
+	}
+}
+
+func TestConvertSchemaFieldsBinary(t *testing.T) {
+	// Round-trip through the binary format, which only has the projected
+	// fields.
+	in, err := os.ReadFile(filepath.Join("testdata", "people.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bin, out, stderr bytes.Buffer
+	args := []string{"convert", "-from", "json", "-to", "binary", "-schema", "id:uint8,name,note,score:float64", "-fields", "name,id"}
+	if code := run(args, bytes.NewReader(in), &bin, &stderr); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr.String())
+	}
+	args = []string{"convert", "-from", "binary", "-to", "csv", "-schema", "name,id:uint8"}
+	if code := run(args, &bin, &out, &stderr); code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr.String())
+	}
+	if want := "name,id\nAnn,1\nBo,2\nCy,3\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
+
+// TestConvertSparseWithoutFields shows the documented limit: CSV columns
+// come from the first record when -fields is not given.
+func TestConvertSparseWithoutFields(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "sparse.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	args := []string{"convert", "-from", "json", "-to", "csv"}
+	if code := run(args, bytes.NewReader(in), &stdout, &stderr); code != 1 {
+		t.Fatalf("exit status %d, want 1: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), `"email"`) {
+		t.Fatalf("error %q does not name the unexpected column", stderr.String())
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("output differs from %s:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
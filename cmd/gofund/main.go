@@ -0,0 +1,86 @@
+// Gofund is a command-line tool built on the packages in this repository.
+//
+// Usage:
+//
+//	gofund <command> [flags]
+//
+// The commands are:
+//
+//	convert   convert records between JSON, NDJSON, CSV and binary
+//	formats   list the record formats convert understands
+//
+// Run "gofund <command> -h" for the flags of a command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ops2go/go-fundamentals/encode"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"convert", "convert records between JSON, NDJSON, CSV and binary", runConvert},
+	{"formats", "list the record formats convert understands", runFormats},
+}
+
+// errUsage reports bad arguments, after the usage has been printed.
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(args[1:], stdin, stdout, stderr)
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+			return 2
+		}
+		fmt.Fprintf(stderr, "gofund %s: %v\n", c.name, err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "gofund: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: gofund <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.short)
+	}
+}
+
+func runFormats(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("formats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for _, c := range encode.Codecs() {
+		fmt.Fprintf(stdout, "%-8s %s\n", c.Name(), c.ContentType())
+	}
+	return nil
+}
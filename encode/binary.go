@@ -0,0 +1,916 @@
+package encode
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// These are the errors that can be wrapped by a BinaryFieldError.
+var (
+	ErrBinaryRange   = errors.New("value out of range")
+	ErrBinaryTooLong = errors.New("length exceeds limit")
+)
+
+var (
+	errBinaryNotStruct    = errors.New("encode: binary codec needs a struct or pointer to a struct")
+	errBinaryNotStructPtr = errors.New("encode: binary decode target must be a non-nil pointer to a struct")
+	errBinaryTrailing     = errors.New("encode: trailing data after binary record")
+)
+
+// A BinaryFieldError describes a struct field that could not be encoded or
+// decoded.
+type BinaryFieldError struct {
+	Field string // the struct field, as Type.Name
+	Err   error
+}
+
+func (e *BinaryFieldError) Error() string {
+	return fmt.Sprintf("encode: binary field %s: %v", e.Field, e.Err)
+}
+
+func (e *BinaryFieldError) Unwrap() error { return e.Err }
+
+// DefaultBinaryMaxLen is the longest string or slice a BinaryDecoder
+// accepts unless its MaxLen is set.
+const DefaultBinaryMaxLen = 64 << 20
+
+// AppendBinary appends the binary record for v, a struct or pointer to a
+// struct, to b.
+//
+// A record is the exported fields of the struct, in order, with no
+// padding or field names. How a field is written is controlled by a tag of
+// the form `bin:"kind,option,..."`:
+//
+//	u8 u16 u32 u64      unsigned fixed-length integer
+//	i8 i16 i32 i64      signed fixed-length integer, two's complement
+//	uvarint varint      variable-length integer as in encoding/binary
+//	f32 f64             IEEE 754 float
+//	bool                one byte, 0 or 1
+//	string bytes        length followed by the content
+//
+// The options are be and le for the byte order, big-endian (network byte
+// order) by default, and len=u8, len=u16, len=u32 or len=uvarint for the
+// length prefix of strings and slices, uvarint by default. A tag of "-"
+// skips the field.
+//
+// Without a kind, the kind follows the Go type: int16 is i16, uint64 is
+// u64, and int and uint, whose size depends on the platform, are varint
+// and uvarint. Any integer field can be given any integer kind; values
+// that do not fit are an ErrBinaryRange error. A slice is written as its
+// length followed by its elements, an array as just its elements, and a
+// nested struct inline; for these the kind and byte order apply to the
+// elements. Types that implement encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler, with value or pointer receivers, are written
+// as a length-prefixed blob. A struct may contain slices of its own type.
+//
+// Appending a struct of fixed-size fields to a buffer with enough
+// capacity does not allocate.
+func AppendBinary(b []byte, v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return b, errBinaryNotStruct
+	}
+	op, err := binaryOpOf(rv.Type())
+	if err != nil {
+		return b, err
+	}
+	return op.append(b, rv)
+}
+
+// MarshalBinary returns the binary record for v, a struct or pointer to a
+// struct. See AppendBinary for the format.
+func MarshalBinary(v any) ([]byte, error) {
+	return AppendBinary(nil, v)
+}
+
+// UnmarshalBinary decodes the binary record in data into the struct
+// pointed to by v. Data must hold exactly one record. Slices in v are
+// reused if they have enough capacity; strings and blobs are copied, so
+// data may be modified afterwards.
+func UnmarshalBinary(data []byte, v any) error {
+	rv, op, err := binaryTarget(v)
+	if err != nil {
+		return err
+	}
+	// The length of data already bounds every length prefix.
+	r := binReader{data: data, maxLen: math.MaxInt}
+	if err := op.decode(&r, rv); err != nil {
+		return err
+	}
+	if r.off != len(data) {
+		return errBinaryTrailing
+	}
+	return nil
+}
+
+// A BinaryEncoder writes binary records to a stream. Records follow each
+// other with no separator, so the reader must know their type. Output is
+// buffered; call Flush when done.
+type BinaryEncoder struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewBinaryEncoder returns an encoder that writes to w.
+func NewBinaryEncoder(w io.Writer) *BinaryEncoder {
+	return &BinaryEncoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes the record for v, a struct or pointer to a struct.
+func (e *BinaryEncoder) Encode(v any) error {
+	b, err := AppendBinary(e.buf[:0], v)
+	e.buf = b
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (e *BinaryEncoder) Flush() error { return e.w.Flush() }
+
+// A BinaryDecoder reads binary records from a stream.
+type BinaryDecoder struct {
+	r binReader
+	// MaxLen is the longest string, byte slice or slice accepted, guarding
+	// against corrupt lengths. Zero means DefaultBinaryMaxLen.
+	MaxLen int
+}
+
+// NewBinaryDecoder returns a decoder that reads from r.
+func NewBinaryDecoder(r io.Reader) *BinaryDecoder {
+	return &BinaryDecoder{r: binReader{r: bufio.NewReader(r)}}
+}
+
+// Decode reads the next record into the struct pointed to by v. It
+// returns io.EOF if the input ends before the record starts and an error
+// wrapping io.ErrUnexpectedEOF if it ends inside it. Decoding a struct of
+// fixed-size fields does not allocate.
+func (d *BinaryDecoder) Decode(v any) error {
+	rv, op, err := binaryTarget(v)
+	if err != nil {
+		return err
+	}
+	if _, err := d.r.r.Peek(1); err != nil {
+		return err
+	}
+	d.r.maxLen = d.MaxLen
+	if d.r.maxLen <= 0 {
+		d.r.maxLen = DefaultBinaryMaxLen
+	}
+	return op.decode(&d.r, rv)
+}
+
+// binaryTarget checks that v is a non-nil pointer to a struct and returns
+// the struct and its codec.
+func binaryTarget(v any) (reflect.Value, *binOp, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, errBinaryNotStructPtr
+	}
+	rv = rv.Elem()
+	op, err := binaryOpOf(rv.Type())
+	return rv, op, err
+}
+
+// A binKind is how a single value is written.
+type binKind uint8
+
+const (
+	binInvalid binKind = iota
+	binBool
+	binU8
+	binU16
+	binU32
+	binU64
+	binI8
+	binI16
+	binI32
+	binI64
+	binUvarint
+	binVarint
+	binF32
+	binF64
+	binString
+	binBytes
+	binSlice
+	binArray
+	binStruct
+	binMarshaler
+)
+
+var binKindNames = [...]string{
+	binBool:    "bool",
+	binU8:      "u8",
+	binU16:     "u16",
+	binU32:     "u32",
+	binU64:     "u64",
+	binI8:      "i8",
+	binI16:     "i16",
+	binI32:     "i32",
+	binI64:     "i64",
+	binUvarint: "uvarint",
+	binVarint:  "varint",
+	binF32:     "f32",
+	binF64:     "f64",
+	binString:  "string",
+	binBytes:   "bytes",
+}
+
+func (k binKind) String() string {
+	if int(k) < len(binKindNames) && binKindNames[k] != "" {
+		return binKindNames[k]
+	}
+	return fmt.Sprintf("binKind(%d)", k)
+}
+
+// size returns the encoded size of a fixed-size kind, or -1.
+func (k binKind) size() int {
+	switch k {
+	case binBool, binU8, binI8:
+		return 1
+	case binU16, binI16:
+		return 2
+	case binU32, binI32, binF32:
+		return 4
+	case binU64, binI64, binF64:
+		return 8
+	}
+	return -1
+}
+
+func (k binKind) unsigned() bool { return binU8 <= k && k <= binU64 || k == binUvarint }
+func (k binKind) signed() bool   { return binI8 <= k && k <= binI64 || k == binVarint }
+
+// bits returns the width of an integer kind.
+func (k binKind) bits() int {
+	if k == binUvarint || k == binVarint {
+		return 64
+	}
+	return 8 * k.size()
+}
+
+// byteOrder is implemented by binary.BigEndian and binary.LittleEndian.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// A binOp encodes and decodes one Go type.
+type binOp struct {
+	kind    binKind
+	order   byteOrder
+	lenKind binKind    // length prefix of strings, blobs and slices
+	elem    *binOp     // element of a slice or array
+	n       int        // length of an array
+	fields  []binField // fields of a struct
+	typ     reflect.Type
+	size    int  // encoded size if fixed, or -1
+	ptr     bool // MarshalBinary has a pointer receiver
+}
+
+type binField struct {
+	index  int
+	name   string
+	offset int // offset in a fixed-size struct
+	op     *binOp
+}
+
+// binTag is a parsed `bin` struct tag.
+type binTag struct {
+	kind    binKind
+	order   byteOrder
+	lenKind binKind
+}
+
+func parseBinTag(tag string) (binTag, error) {
+	t := binTag{order: binary.BigEndian, lenKind: binUvarint}
+	kind, opts, _ := strings.Cut(tag, ",")
+	if kind != "" {
+		if t.kind = binKindOf(kind); t.kind == binInvalid {
+			return t, fmt.Errorf("unknown kind %q", kind)
+		}
+	}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		switch {
+		case opt == "be":
+			t.order = binary.BigEndian
+		case opt == "le":
+			t.order = binary.LittleEndian
+		case strings.HasPrefix(opt, "len="):
+			switch t.lenKind = binKindOf(opt[len("len="):]); t.lenKind {
+			case binU8, binU16, binU32, binUvarint:
+			default:
+				return t, fmt.Errorf("invalid length prefix %q", opt)
+			}
+		default:
+			return t, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return t, nil
+}
+
+func binKindOf(name string) binKind {
+	for k, s := range binKindNames {
+		if s != "" && s == name {
+			return binKind(k)
+		}
+	}
+	return binInvalid
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeFor[encoding.BinaryMarshaler]()
+	binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+)
+
+// binOpCacheEntry is the compiled codec for a struct type, or the reason
+// it has none.
+type binOpCacheEntry struct {
+	op  *binOp
+	err error
+}
+
+var binOpCache sync.Map // map[reflect.Type]binOpCacheEntry
+
+// binaryOpOf returns the codec for struct type t.
+func binaryOpOf(t reflect.Type) (*binOp, error) {
+	if e, ok := binOpCache.Load(t); ok {
+		return e.(binOpCacheEntry).op, e.(binOpCacheEntry).err
+	}
+	op, err := compileBinary(t, binTag{order: binary.BigEndian, lenKind: binUvarint}, make(map[reflect.Type]*binOp))
+	e, _ := binOpCache.LoadOrStore(t, binOpCacheEntry{op, err})
+	return e.(binOpCacheEntry).op, e.(binOpCacheEntry).err
+}
+
+// compileBinary compiles the codec for t. Seen holds the structs being
+// compiled, so that a struct containing a slice of itself refers back to
+// its own codec instead of recursing forever.
+func compileBinary(t reflect.Type, tag binTag, seen map[reflect.Type]*binOp) (*binOp, error) {
+	op := &binOp{kind: tag.kind, order: tag.order, lenKind: tag.lenKind, typ: t, size: -1}
+	pt := reflect.PointerTo(t)
+	if tag.kind == binInvalid && pt.Implements(binaryMarshalerType) && pt.Implements(binaryUnmarshalerType) {
+		op.kind = binMarshaler
+		op.ptr = !t.Implements(binaryMarshalerType)
+		return op, nil
+	}
+
+	switch k := t.Kind(); k {
+	case reflect.Struct:
+		if tag.kind != binInvalid {
+			return nil, fmt.Errorf("kind %s for struct %s", tag.kind, t)
+		}
+		if op, ok := seen[t]; ok {
+			return op, nil
+		}
+		return compileBinaryStruct(t, seen)
+	case reflect.Array, reflect.Slice:
+		if k == reflect.Slice && t.Elem().Kind() == reflect.Uint8 && (tag.kind == binInvalid || tag.kind == binBytes || tag.kind == binU8) {
+			op.kind = binBytes
+			return op, nil
+		}
+		elemTag := tag
+		if tag.kind == binBytes {
+			elemTag.kind = binU8
+		}
+		elem, err := compileBinary(t.Elem(), elemTag, seen)
+		if err != nil {
+			return nil, err
+		}
+		op.elem = elem
+		if k == reflect.Slice {
+			op.kind = binSlice
+			return op, nil
+		}
+		op.kind, op.n = binArray, t.Len()
+		if elem.size >= 0 {
+			op.size = op.n * elem.size
+		}
+		return op, nil
+	case reflect.String:
+		if tag.kind == binInvalid {
+			op.kind = binString
+		}
+		if op.kind != binString {
+			return nil, fmt.Errorf("kind %s for %s", op.kind, t)
+		}
+		return op, nil
+	case reflect.Bool:
+		if tag.kind == binInvalid {
+			op.kind = binBool
+		}
+		if op.kind != binBool {
+			return nil, fmt.Errorf("kind %s for %s", op.kind, t)
+		}
+	case reflect.Float32, reflect.Float64:
+		if tag.kind == binInvalid {
+			op.kind = binF64
+			if k == reflect.Float32 {
+				op.kind = binF32
+			}
+		}
+		if op.kind != binF32 && op.kind != binF64 {
+			return nil, fmt.Errorf("kind %s for %s", op.kind, t)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if tag.kind == binInvalid {
+			op.kind = defaultBinKinds[k]
+		}
+		if !op.kind.unsigned() && !op.kind.signed() {
+			return nil, fmt.Errorf("kind %s for %s", op.kind, t)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+	op.size = op.kind.size()
+	return op, nil
+}
+
+var defaultBinKinds = map[reflect.Kind]binKind{
+	reflect.Int:     binVarint,
+	reflect.Int8:    binI8,
+	reflect.Int16:   binI16,
+	reflect.Int32:   binI32,
+	reflect.Int64:   binI64,
+	reflect.Uint:    binUvarint,
+	reflect.Uint8:   binU8,
+	reflect.Uint16:  binU16,
+	reflect.Uint32:  binU32,
+	reflect.Uint64:  binU64,
+	reflect.Uintptr: binUvarint,
+}
+
+func compileBinaryStruct(t reflect.Type, seen map[reflect.Type]*binOp) (*binOp, error) {
+	// Register the codec before compiling the fields so that a recursive
+	// reference finds it. Recursion must go through a slice, so the
+	// struct's size is never needed while it is being compiled.
+	op := &binOp{kind: binStruct, typ: t, size: 0}
+	seen[t] = op
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		s := sf.Tag.Get("bin")
+		if s == "-" || !sf.IsExported() {
+			continue
+		}
+		name := t.Name() + "." + sf.Name
+		tag, err := parseBinTag(s)
+		if err != nil {
+			return nil, &BinaryFieldError{Field: name, Err: err}
+		}
+		fop, err := compileBinary(sf.Type, tag, seen)
+		if err != nil {
+			var fe *BinaryFieldError
+			if !errors.As(err, &fe) {
+				err = &BinaryFieldError{Field: name, Err: err}
+			}
+			return nil, err
+		}
+		op.fields = append(op.fields, binField{index: i, name: name, offset: op.size, op: fop})
+		if op.size >= 0 && fop.size >= 0 {
+			op.size += fop.size
+		} else {
+			op.size = -1
+		}
+	}
+	return op, nil
+}
+
+// wrap attributes err to the field unless a nested field already claims it.
+func (f *binField) wrap(err error) error {
+	if _, ok := err.(*BinaryFieldError); ok {
+		return err
+	}
+	return &BinaryFieldError{Field: f.name, Err: err}
+}
+
+// append appends the encoding of v to b.
+func (op *binOp) append(b []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	switch k := op.kind; k {
+	case binStruct:
+		for i := range op.fields {
+			f := &op.fields[i]
+			if b, err = f.op.append(b, v.Field(f.index)); err != nil {
+				return b, f.wrap(err)
+			}
+		}
+	case binArray, binSlice:
+		n := v.Len()
+		if k == binSlice {
+			if b, err = op.appendLen(b, n); err != nil {
+				return b, err
+			}
+		}
+		for i := range n {
+			if b, err = op.elem.append(b, v.Index(i)); err != nil {
+				return b, err
+			}
+		}
+	case binString:
+		s := v.String()
+		if b, err = op.appendLen(b, len(s)); err != nil {
+			return b, err
+		}
+		b = append(b, s...)
+	case binBytes:
+		p := v.Bytes()
+		if b, err = op.appendLen(b, len(p)); err != nil {
+			return b, err
+		}
+		b = append(b, p...)
+	case binMarshaler:
+		switch {
+		case v.CanAddr():
+			v = v.Addr()
+		case op.ptr:
+			// MarshalBinary needs a pointer; call it on a copy.
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			v = p
+		}
+		p, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return b, err
+		}
+		if b, err = op.appendLen(b, len(p)); err != nil {
+			return b, err
+		}
+		b = append(b, p...)
+	case binBool:
+		if v.Bool() {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	case binF32:
+		f := v.Float()
+		if math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+			return b, fmt.Errorf("%w: %v for %s", ErrBinaryRange, f, k)
+		}
+		b = op.order.AppendUint32(b, math.Float32bits(float32(f)))
+	case binF64:
+		b = op.order.AppendUint64(b, math.Float64bits(v.Float()))
+	case binUvarint:
+		u, err := binUint(v, 64)
+		if err != nil {
+			return b, err
+		}
+		b = binary.AppendUvarint(b, u)
+	case binVarint:
+		i, err := binInt(v, 64)
+		if err != nil {
+			return b, err
+		}
+		b = binary.AppendVarint(b, i)
+	default:
+		var u uint64
+		if k.unsigned() {
+			u, err = binUint(v, k.bits())
+		} else {
+			var i int64
+			i, err = binInt(v, k.bits())
+			u = uint64(i)
+		}
+		if err != nil {
+			return b, fmt.Errorf("%w for %s", err, k)
+		}
+		b = appendFixed(b, op.order, k.size(), u)
+	}
+	return b, nil
+}
+
+// appendLen appends the length prefix n.
+func (op *binOp) appendLen(b []byte, n int) ([]byte, error) {
+	if op.lenKind != binUvarint && uint64(n)>>op.lenKind.bits() != 0 {
+		return b, fmt.Errorf("%w: %d for len=%s", ErrBinaryTooLong, n, op.lenKind)
+	}
+	if op.lenKind == binUvarint {
+		return binary.AppendUvarint(b, uint64(n)), nil
+	}
+	return appendFixed(b, op.order, op.lenKind.size(), uint64(n)), nil
+}
+
+func appendFixed(b []byte, order byteOrder, size int, u uint64) []byte {
+	switch size {
+	case 1:
+		return append(b, byte(u))
+	case 2:
+		return order.AppendUint16(b, uint16(u))
+	case 4:
+		return order.AppendUint32(b, uint32(u))
+	}
+	return order.AppendUint64(b, u)
+}
+
+// binUint returns the integer in v as an unsigned value of the given width.
+func binUint(v reflect.Value, bits int) (uint64, error) {
+	var u uint64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		if i < 0 {
+			return 0, fmt.Errorf("%w: %d", ErrBinaryRange, i)
+		}
+		u = uint64(i)
+	default:
+		u = v.Uint()
+	}
+	if bits < 64 && u>>bits != 0 {
+		return 0, fmt.Errorf("%w: %d", ErrBinaryRange, u)
+	}
+	return u, nil
+}
+
+// binInt returns the integer in v as a signed value of the given width.
+func binInt(v reflect.Value, bits int) (int64, error) {
+	var i int64
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %d", ErrBinaryRange, u)
+		}
+		i = int64(u)
+	default:
+		i = v.Int()
+	}
+	if bits < 64 && (i < -1<<(bits-1) || i >= 1<<(bits-1)) {
+		return 0, fmt.Errorf("%w: %d", ErrBinaryRange, i)
+	}
+	return i, nil
+}
+
+// A binReader is the input of a decode: either a byte slice or a stream.
+type binReader struct {
+	data   []byte // input when decoding from memory
+	off    int
+	r      *bufio.Reader // input when decoding from a stream
+	buf    []byte        // holds values longer than r's buffer
+	maxLen int
+}
+
+// next returns the next n bytes of input. They are valid until the next
+// call.
+func (r *binReader) next(n int) ([]byte, error) {
+	if r.r == nil {
+		if len(r.data)-r.off < n {
+			r.off = len(r.data)
+			return nil, io.ErrUnexpectedEOF
+		}
+		p := r.data[r.off : r.off+n]
+		r.off += n
+		return p, nil
+	}
+	if n <= r.r.Size() {
+		p, err := r.r.Peek(n)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		r.r.Discard(n)
+		return p, nil
+	}
+	// Read in chunks that double in size, so that a corrupt length
+	// allocates in proportion to the input actually there.
+	p := r.buf[:0]
+	for len(p) < n {
+		m := min(n-len(p), max(len(p), r.r.Size()))
+		p = slices.Grow(p, m)
+		k, err := io.ReadFull(r.r, p[len(p):len(p)+m])
+		p = p[:len(p)+k]
+		if err != nil {
+			r.buf = p
+			return nil, unexpectedEOF(err)
+		}
+	}
+	r.buf = p
+	return p, nil
+}
+
+// readUvarint reads a uvarint without r escaping to the heap, as it would
+// if passed to binary.ReadUvarint as an io.ByteReader.
+func (r *binReader) readUvarint() (uint64, error) {
+	if r.r != nil {
+		u, err := binary.ReadUvarint(r.r)
+		return u, unexpectedEOF(err)
+	}
+	u, n := binary.Uvarint(r.data[r.off:])
+	switch {
+	case n == 0:
+		return 0, io.ErrUnexpectedEOF
+	case n < 0:
+		return 0, fmt.Errorf("%w: uvarint overflows 64 bits", ErrBinaryRange)
+	}
+	r.off += n
+	return u, nil
+}
+
+func (r *binReader) readVarint() (int64, error) {
+	u, err := r.readUvarint()
+	// Undo the zig-zag encoding of binary.AppendVarint.
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+// readLen reads a length prefix and checks it against the limit and, in
+// memory, the remaining input. Every element takes at least a byte, except
+// those of zero size, whose slices are limited the same way so that a
+// corrupt length cannot make decoding spin.
+func (r *binReader) readLen(op *binOp) (int, error) {
+	var n uint64
+	if op.lenKind == binUvarint {
+		var err error
+		if n, err = r.readUvarint(); err != nil {
+			return 0, err
+		}
+	} else {
+		p, err := r.next(op.lenKind.size())
+		if err != nil {
+			return 0, err
+		}
+		n = readFixed(p, op.order, op.lenKind.size())
+	}
+	if n > uint64(r.maxLen) {
+		return 0, fmt.Errorf("%w: %d", ErrBinaryTooLong, n)
+	}
+	if r.r == nil && n > uint64(len(r.data)-r.off) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func readFixed(p []byte, order byteOrder, size int) uint64 {
+	switch size {
+	case 1:
+		return uint64(p[0])
+	case 2:
+		return uint64(order.Uint16(p))
+	case 4:
+		return uint64(order.Uint32(p))
+	}
+	return order.Uint64(p)
+}
+
+// decode reads a value into v.
+func (op *binOp) decode(r *binReader, v reflect.Value) error {
+	if op.size >= 0 {
+		p, err := r.next(op.size)
+		if err != nil {
+			return err
+		}
+		return op.decodeFixed(p, v)
+	}
+
+	switch op.kind {
+	case binStruct:
+		for i := range op.fields {
+			f := &op.fields[i]
+			if err := f.op.decode(r, v.Field(f.index)); err != nil {
+				return f.wrap(err)
+			}
+		}
+	case binArray:
+		for i := range op.n {
+			if err := op.elem.decode(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case binSlice:
+		n, err := r.readLen(op)
+		if err != nil {
+			return err
+		}
+		// Grow the slice as elements arrive rather than trusting n up
+		// front: a corrupt length would otherwise allocate up to MaxLen
+		// elements before the input runs out.
+		v.SetLen(min(n, v.Cap()))
+		for i := range n {
+			if i == v.Len() {
+				v.Grow(1)
+				v.SetLen(i + 1)
+			}
+			if err := op.elem.decode(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case binString, binBytes, binMarshaler:
+		n, err := r.readLen(op)
+		if err != nil {
+			return err
+		}
+		p, err := r.next(n)
+		if err != nil {
+			return err
+		}
+		switch op.kind {
+		case binString:
+			v.SetString(string(p))
+		case binBytes:
+			v.SetBytes(append(v.Bytes()[:0], p...))
+		default:
+			return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(p)
+		}
+	case binUvarint:
+		u, err := r.readUvarint()
+		if err != nil {
+			return err
+		}
+		return setBinUint(v, u)
+	case binVarint:
+		i, err := r.readVarint()
+		if err != nil {
+			return err
+		}
+		return setBinInt(v, i)
+	}
+	return nil
+}
+
+// decodeFixed decodes a fixed-size value from p, which holds exactly its
+// encoding.
+func (op *binOp) decodeFixed(p []byte, v reflect.Value) error {
+	switch k := op.kind; k {
+	case binStruct:
+		for i := range op.fields {
+			f := &op.fields[i]
+			if err := f.op.decodeFixed(p[f.offset:], v.Field(f.index)); err != nil {
+				return f.wrap(err)
+			}
+		}
+	case binArray:
+		if op.elem.kind == binU8 && v.Type().Elem().Kind() == reflect.Uint8 {
+			copy(v.Bytes(), p)
+			return nil
+		}
+		for i := range op.n {
+			if err := op.elem.decodeFixed(p[i*op.elem.size:], v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case binBool:
+		v.SetBool(p[0] != 0)
+	case binF32:
+		v.SetFloat(float64(math.Float32frombits(op.order.Uint32(p))))
+	case binF64:
+		v.SetFloat(math.Float64frombits(op.order.Uint64(p)))
+	default:
+		u := readFixed(p, op.order, k.size())
+		if k.unsigned() {
+			return setBinUint(v, u)
+		}
+		// Sign-extend from the width of the kind.
+		shift := 64 - k.bits()
+		return setBinInt(v, int64(u<<shift)>>shift)
+	}
+	return nil
+}
+
+func setBinUint(v reflect.Value, u uint64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if u > math.MaxInt64 || v.OverflowInt(int64(u)) {
+			return fmt.Errorf("%w: %d for %s", ErrBinaryRange, u, v.Type())
+		}
+		v.SetInt(int64(u))
+	default:
+		if v.OverflowUint(u) {
+			return fmt.Errorf("%w: %d for %s", ErrBinaryRange, u, v.Type())
+		}
+		v.SetUint(u)
+	}
+	return nil
+}
+
+func setBinInt(v reflect.Value, i int64) error {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("%w: %d for %s", ErrBinaryRange, i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	default:
+		if v.OverflowInt(i) {
+			return fmt.Errorf("%w: %d for %s", ErrBinaryRange, i, v.Type())
+		}
+		v.SetInt(i)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package encode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type binNode struct {
+	Name     string
+	Children []binNode
+}
+
+func TestBinaryRecursive(t *testing.T) {
+	in := binNode{"root", []binNode{{"a", nil}, {"b", []binNode{{"c", nil}}}}}
+	b, err := MarshalBinary(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out binNode
+	if err := UnmarshalBinary(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("decoded %+v, want %+v", out, in)
+	}
+}
+
+type binBig struct {
+	A, B, C, D int64
+}
+
+func TestBinaryCorruptLength(t *testing.T) {
+	type items struct{ Items []binBig }
+	type blob struct{ P []byte }
+	// A uvarint length of 58720256, under DefaultBinaryMaxLen, and no
+	// content.
+	in := []byte{0x80, 0x80, 0x80, 0x1c}
+
+	for _, v := range []any{&items{}, &blob{}} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := NewBinaryDecoder(bytes.NewReader(in)).Decode(v)
+		runtime.ReadMemStats(&after)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("Decode(%T) = %v, want %v", v, err, io.ErrUnexpectedEOF)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Fatalf("Decode(%T) allocated %d bytes for a truncated record", v, n)
+		}
+	}
+}
+
+func TestBinaryLongStream(t *testing.T) {
+	type rec struct{ P []byte }
+	in := rec{bytes.Repeat([]byte("0123456789"), 100_000)}
+	b, err := MarshalBinary(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out rec
+	if err := NewBinaryDecoder(bytes.NewReader(b)).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.P, in.P) {
+		t.Fatal("decoded blob differs")
+	}
+}
+
+// binStamp marshals through pointer receivers only.
+type binStamp struct{ n int }
+
+func (s *binStamp) MarshalBinary() ([]byte, error) {
+	return []byte(strconv.Itoa(s.n)), nil
+}
+
+func (s *binStamp) UnmarshalBinary(p []byte) error {
+	n, err := strconv.Atoi(string(p))
+	s.n = n
+	return err
+}
+
+func TestBinaryPointerMarshaler(t *testing.T) {
+	type rec struct {
+		Stamp binStamp
+		At    time.Time
+	}
+	in := rec{binStamp{42}, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	// Passing rec by value makes its fields unaddressable.
+	for _, v := range []any{in, &in} {
+		b, err := MarshalBinary(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out rec
+		if err := UnmarshalBinary(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Stamp != in.Stamp || !out.At.Equal(in.At) {
+			t.Fatalf("decoded %+v, want %+v", out, in)
+		}
+	}
+}
+
+type binFuzz struct {
+	A    uint8
+	B    int16 `bin:",le"`
+	C    int   `bin:"i32"`
+	D    uint
+	S    string `bin:",len=u8"`
+	P    []byte
+	L    []int32
+	N    []binNode `bin:",len=u16"`
+	Arr  [2]uint16
+	Flag bool
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, v := range []binFuzz{
+		{},
+		{A: 1, B: -2, C: 3, D: 4, S: "five", P: []byte{6}, L: []int32{7, -8}, Arr: [2]uint16{9, 10}, Flag: true},
+		{N: []binNode{{"x", []binNode{{"y", nil}}}}},
+	} {
+		b, err := MarshalBinary(v)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add([]byte{0x80, 0x80, 0x80, 0x1c})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v binFuzz
+		err := UnmarshalBinary(data, &v)
+
+		var sv binFuzz
+		serr := NewBinaryDecoder(bytes.NewReader(data)).Decode(&sv)
+		if err == nil && serr != nil {
+			t.Fatalf("UnmarshalBinary succeeded but Decode = %v", serr)
+		}
+		if err != nil {
+			return
+		}
+
+		// The input may not be canonical, so compare re-encodings.
+		b1, err := MarshalBinary(v)
+		if err != nil {
+			t.Fatalf("MarshalBinary of decoded value: %v", err)
+		}
+		var v2 binFuzz
+		if err := UnmarshalBinary(b1, &v2); err != nil {
+			t.Fatalf("UnmarshalBinary of re-encoding: %v", err)
+		}
+		b2, err := MarshalBinary(v2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Fatalf("round trip changed encoding:\n% x\n% x", b1, b2)
+		}
+	})
+}
+
+type binGolden struct {
+	A uint16   `bin:"u16"`
+	B uint16   `bin:"u16,le"`
+	C int32    `bin:"i32,be"`
+	D int      `bin:"i32,le"`
+	E uint32   `bin:",le"`
+	F int64    `bin:"u8"`
+	G uint     // uvarint
+	H int16    // i16, big-endian
+	S string   `bin:",len=u8"`
+	P []byte   `bin:",len=u16"`
+	L []uint16 `bin:",le,len=u8"`
+	T bool
+}
+
+var (
+	binGoldenValue = binGolden{
+		A: 0x0102, B: 0x0102, C: -2, D: -2, E: 0x01020304, F: 200, G: 300, H: -3,
+		S: "hi", P: []byte{7}, L: []uint16{1, 2}, T: true,
+	}
+	binGoldenBytes = []byte{
+		0x01, 0x02, // A u16
+		0x02, 0x01, // B u16,le
+		0xff, 0xff, 0xff, 0xfe, // C i32,be
+		0xfe, 0xff, 0xff, 0xff, // D i32,le
+		0x04, 0x03, 0x02, 0x01, // E le
+		0xc8,       // F u8
+		0xac, 0x02, // G uvarint
+		0xff, 0xfd, // H i16
+		0x02, 'h', 'i', // S len=u8
+		0x00, 0x01, 0x07, // P len=u16
+		0x02, 0x01, 0x00, 0x02, 0x00, // L le,len=u8
+		0x01, // T
+	}
+)
+
+func TestBinaryGolden(t *testing.T) {
+	b, err := MarshalBinary(&binGoldenValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, binGoldenBytes) {
+		t.Fatalf("MarshalBinary = % x\nwant            % x", b, binGoldenBytes)
+	}
+	var out binGolden
+	if err := UnmarshalBinary(binGoldenBytes, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, binGoldenValue) {
+		t.Fatalf("decoded %+v, want %+v", out, binGoldenValue)
+	}
+}
+
+func TestBinaryTagLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		err  error
+	}{
+		{"u16 negative", &struct {
+			A int `bin:"u16"`
+		}{-1}, ErrBinaryRange},
+		{"u16 too large", &struct {
+			A uint32 `bin:"u16"`
+		}{1 << 16}, ErrBinaryRange},
+		{"i32 too large", &struct {
+			A int64 `bin:"i32,le"`
+		}{1 << 40}, ErrBinaryRange},
+		{"len=u8 too long", &struct {
+			S string `bin:",len=u8"`
+		}{string(make([]byte, 256))}, ErrBinaryTooLong},
+	}
+	for _, tt := range tests {
+		if _, err := MarshalBinary(tt.v); !errors.Is(err, tt.err) {
+			t.Errorf("%s: MarshalBinary = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	// Decoding checks the range of the destination field too.
+	var small struct {
+		A int8 `bin:"u16"`
+	}
+	if err := UnmarshalBinary([]byte{0x01, 0x00}, &small); !errors.Is(err, ErrBinaryRange) {
+		t.Errorf("UnmarshalBinary = %v, want %v", err, ErrBinaryRange)
+	}
+}
+
+type binFixed struct {
+	A uint16 `bin:"u16"`
+	B int32  `bin:"i32,le"`
+	C float64
+	D bool
+	E [4]byte
+	F uint64 `bin:"uvarint"`
+	G int    `bin:"i16"`
+}
+
+func TestBinaryFixedAllocs(t *testing.T) {
+	in := binFixed{A: 1, B: -2, C: 3.5, D: true, E: [4]byte{1, 2, 3, 4}, F: 1 << 40, G: -7}
+	buf := make([]byte, 0, 64)
+	var err error
+	if n := testing.AllocsPerRun(100, func() {
+		if buf, err = AppendBinary(buf[:0], &in); err != nil {
+			t.Fatal(err)
+		}
+	}); n != 0 {
+		t.Errorf("AppendBinary allocated %v times per record", n)
+	}
+
+	var out binFixed
+	if n := testing.AllocsPerRun(100, func() {
+		if err := UnmarshalBinary(buf, &out); err != nil {
+			t.Fatal(err)
+		}
+	}); n != 0 {
+		t.Errorf("UnmarshalBinary allocated %v times per record", n)
+	}
+	if out != in {
+		t.Fatalf("decoded %+v, want %+v", out, in)
+	}
+
+	// AllocsPerRun calls the function once more than asked, to warm up.
+	dec := NewBinaryDecoder(bytes.NewReader(bytes.Repeat(buf, 101)))
+	out = binFixed{}
+	if n := testing.AllocsPerRun(100, func() {
+		if err := dec.Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+	}); n != 0 {
+		t.Errorf("Decode allocated %v times per record", n)
+	}
+	if out != in {
+		t.Fatalf("decoded %+v, want %+v", out, in)
+	}
+}
@@ -0,0 +1,407 @@
+package encode
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An Encoder writes records to a stream. Close finishes the stream, for
+// example writing a closing bracket, and flushes it; it does not close the
+// underlying writer.
+type Encoder interface {
+	Encode(v any) error
+	Close() error
+}
+
+// A Decoder reads records from a stream. Decode returns io.EOF after the
+// last record.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// A Codec is a record format that can be looked up by name, content type
+// or file extension, so that tools can read and write any registered
+// format without knowing it.
+type Codec interface {
+	Name() string
+	ContentType() string // media type, without parameters
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// The built-in codecs, registered under their names. JSON reads both JSON
+// arrays and streams of values, and writes an array; NDJSON writes one
+// value per line. CSV and Binary need struct records, except that CSV also
+// accepts maps, as described for CSV. Binary cannot decode into or encode
+// a *map[string]any, so converting to or from it needs a newRecord that
+// returns a pointer to a struct. Gob uses encoding/gob.
+var (
+	JSON   Codec = jsonCodec{name: "json", contentType: "application/json"}
+	NDJSON Codec = jsonCodec{name: "ndjson", contentType: "application/x-ndjson", lines: true}
+	CSV    Codec = csvCodec{}
+	Binary Codec = binaryCodec{}
+	Gob    Codec = gobCodec{}
+)
+
+var registry struct {
+	sync.RWMutex
+	byName map[string]Codec
+	byType map[string]Codec
+	byExt  map[string]Codec
+}
+
+func init() {
+	Register(JSON, ".json")
+	Register(NDJSON, ".ndjson", ".jsonl")
+	Register(CSV, ".csv")
+	Register(Binary, ".bin")
+	Register(Gob, ".gob")
+}
+
+// Register makes a codec available by its name, its content type and the
+// given file extensions, such as ".csv". Names, content types and
+// extensions are matched case-insensitively. Register panics if the name
+// is already registered; a content type or extension that is already
+// taken keeps its earlier codec.
+func Register(c Codec, extensions ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.byName == nil {
+		registry.byName = make(map[string]Codec)
+		registry.byType = make(map[string]Codec)
+		registry.byExt = make(map[string]Codec)
+	}
+	name := strings.ToLower(c.Name())
+	if _, dup := registry.byName[name]; dup {
+		panic("encode: Register called twice for codec " + name)
+	}
+	registry.byName[name] = c
+	if ct := strings.ToLower(c.ContentType()); ct != "" {
+		if _, dup := registry.byType[ct]; !dup {
+			registry.byType[ct] = c
+		}
+	}
+	for _, ext := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if _, dup := registry.byExt[ext]; !dup {
+			registry.byExt[ext] = c
+		}
+	}
+}
+
+// Lookup returns the codec registered under name.
+func Lookup(name string) (Codec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	c, ok := registry.byName[strings.ToLower(name)]
+	return c, ok
+}
+
+// ForContentType returns the codec for a media type such as
+// "text/csv; charset=utf-8". Parameters are ignored.
+func ForContentType(contentType string) (Codec, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	c, ok := registry.byType[mt]
+	return c, ok
+}
+
+// ForFile returns the codec for the extension of a file name, or for an
+// extension such as ".csv" on its own.
+func ForFile(name string) (Codec, bool) {
+	ext := filepath.Ext(name)
+	if ext == "" && !strings.ContainsAny(name, `/\`) {
+		ext = "." + name
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	c, ok := registry.byExt[strings.ToLower(ext)]
+	return c, ok
+}
+
+// Codecs returns the registered codecs sorted by name.
+func Codecs() []Codec {
+	registry.RLock()
+	defer registry.RUnlock()
+	cs := make([]Codec, 0, len(registry.byName))
+	for _, c := range registry.byName {
+		cs = append(cs, c)
+	}
+	slices.SortFunc(cs, func(a, b Codec) int { return strings.Compare(a.Name(), b.Name()) })
+	return cs
+}
+
+// Convert copies records from src to dst until src returns io.EOF, and
+// returns how many it copied. Each record is decoded into a new value
+// from newRecord, which should return a pointer; if newRecord is nil,
+// records are decoded as *map[string]any, which the Binary codec does not
+// support. Convert does not close dst.
+func Convert(dst Encoder, src Decoder, newRecord func() any) (int, error) {
+	if newRecord == nil {
+		_, binDst := dst.(binaryCodecEncoder)
+		_, binSrc := src.(*BinaryDecoder)
+		if binDst || binSrc {
+			return 0, errConvertBinaryMap
+		}
+		newRecord = func() any { return new(map[string]any) }
+	}
+	n := 0
+	for {
+		v := newRecord()
+		if err := src.Decode(v); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, fmt.Errorf("encode: record %d: %w", n+1, err)
+		}
+		if err := dst.Encode(v); err != nil {
+			return n, fmt.Errorf("encode: record %d: %w", n+1, err)
+		}
+		n++
+	}
+}
+
+type jsonCodec struct {
+	name        string
+	contentType string
+	lines       bool
+}
+
+func (c jsonCodec) Name() string        { return c.name }
+func (c jsonCodec) ContentType() string { return c.contentType }
+
+func (c jsonCodec) NewEncoder(w io.Writer) Encoder {
+	if c.lines {
+		return NewNDJSONEncoder(w)
+	}
+	return NewJSONEncoder(w)
+}
+
+func (c jsonCodec) NewDecoder(r io.Reader) Decoder { return NewJSONDecoder(r) }
+
+// The CSV codec encodes and decodes structs as CSVEncoder and CSVDecoder
+// do. It also decodes records into a *map[string]string, *map[string]any
+// or *any, keyed by header, and encodes maps with string keys, taking the
+// header from the sorted keys of the first record. Map values that are
+// not strings are written with strconv, or as JSON for slices and maps.
+//
+// Before the first record, the encoder's SetHeader method fixes the
+// columns of map records and their order, and its OmitHeader method stops
+// it writing a header at all.
+type csvCodec struct{}
+
+func (csvCodec) Name() string        { return "csv" }
+func (csvCodec) ContentType() string { return "text/csv" }
+
+func (csvCodec) NewEncoder(w io.Writer) Encoder {
+	cw := NewCSVWriter(w)
+	return &csvCodecEncoder{w: cw, enc: NewCSVEncoder(cw)}
+}
+
+func (csvCodec) NewDecoder(r io.Reader) Decoder {
+	cr := NewCSVReader(r)
+	return &csvCodecDecoder{r: cr, dec: NewCSVDecoder(cr)}
+}
+
+var errCSVMapKey = errors.New("encode: CSV map records need string keys")
+
+type csvCodecEncoder struct {
+	w        *CSVWriter
+	enc      *CSVEncoder
+	header   []string // set by SetHeader or the first map record
+	record   []string
+	started  bool // the header is settled
+	noHeader bool
+}
+
+// SetHeader sets the columns of map records, in order.
+func (e *csvCodecEncoder) SetHeader(header []string) {
+	e.header = slices.Clone(header)
+}
+
+// OmitHeader makes the encoder write records without a header.
+func (e *csvCodecEncoder) OmitHeader() {
+	e.noHeader = true
+	e.enc.NoHeader = true
+}
+
+func (e *csvCodecEncoder) Encode(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Map {
+		return e.enc.Encode(v)
+	}
+	if rv.Type().Key().Kind() != reflect.String {
+		return errCSVMapKey
+	}
+
+	if !e.started {
+		e.started = true
+		if e.header == nil {
+			for _, k := range rv.MapKeys() {
+				e.header = append(e.header, k.String())
+			}
+			slices.Sort(e.header)
+		}
+		e.record = make([]string, len(e.header))
+		if !e.noHeader {
+			if err := e.w.Write(e.header); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range rv.MapKeys() {
+		if !slices.Contains(e.header, k.String()) {
+			return fmt.Errorf("encode: CSV record has column %q not in header", k.String())
+		}
+	}
+	for i, col := range e.header {
+		s, err := formatCSVValue(rv.MapIndex(reflect.ValueOf(col).Convert(rv.Type().Key())))
+		if err != nil {
+			return &CSVFieldError{Header: col, Field: rv.Type().String(), Err: err}
+		}
+		e.record[i] = s
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvCodecEncoder) Close() error { return e.enc.Flush() }
+
+// formatCSVValue formats a map value for a CSV field. A missing or nil
+// value is an empty field.
+func formatCSVValue(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
+
+type csvCodecDecoder struct {
+	r   *CSVReader
+	dec *CSVDecoder
+}
+
+func (d *csvCodecDecoder) Decode(v any) error {
+	switch m := v.(type) {
+	case *map[string]string, *map[string]any, *any:
+		header, err := d.dec.Header()
+		if err != nil {
+			return err
+		}
+		record, err := d.r.Read()
+		if err != nil {
+			return err
+		}
+		switch m := m.(type) {
+		case *map[string]string:
+			*m = make(map[string]string, len(header))
+			for i, col := range header {
+				if i < len(record) {
+					(*m)[col] = record[i]
+				}
+			}
+		default:
+			row := make(map[string]any, len(header))
+			for i, col := range header {
+				if i < len(record) {
+					row[col] = record[i]
+				}
+			}
+			if p, ok := m.(*any); ok {
+				*p = row
+			} else {
+				*m.(*map[string]any) = row
+			}
+		}
+		return nil
+	}
+	return d.dec.Decode(v)
+}
+
+// The binary codec encodes and decodes structs only, as BinaryEncoder and
+// BinaryDecoder do; its records carry no field names to build a map from.
+type binaryCodec struct{}
+
+var errConvertBinaryMap = errors.New("encode: the binary codec needs struct records; give Convert a newRecord that returns a struct pointer")
+
+func (binaryCodec) Name() string        { return "binary" }
+func (binaryCodec) ContentType() string { return "application/x-binary-record" }
+
+func (binaryCodec) NewEncoder(w io.Writer) Encoder {
+	return binaryCodecEncoder{NewBinaryEncoder(w)}
+}
+
+func (binaryCodec) NewDecoder(r io.Reader) Decoder { return NewBinaryDecoder(r) }
+
+type binaryCodecEncoder struct{ *BinaryEncoder }
+
+func (e binaryCodecEncoder) Close() error { return e.Flush() }
+
+// The gob codec registers map[string]any, []any and json.Number with
+// encoding/gob, so that the generic records Convert uses by default can be
+// encoded.
+type gobCodec struct{}
+
+var registerGob sync.Once
+
+func (gobCodec) Name() string        { return "gob" }
+func (gobCodec) ContentType() string { return "application/x-gob" }
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder {
+	registerGob.Do(registerGobTypes)
+	return gobCodecEncoder{gob.NewEncoder(w)}
+}
+
+func (gobCodec) NewDecoder(r io.Reader) Decoder {
+	registerGob.Do(registerGobTypes)
+	return gob.NewDecoder(r)
+}
+
+func registerGobTypes() {
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register(json.Number(""))
+}
+
+type gobCodecEncoder struct{ *gob.Encoder }
+
+// Close does nothing: a gob.Encoder writes each record as it is encoded.
+func (gobCodecEncoder) Close() error { return nil }
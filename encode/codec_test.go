@@ -0,0 +1,225 @@
+package encode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCSVCodecUnknownColumn(t *testing.T) {
+	var buf bytes.Buffer
+	enc := CSV.NewEncoder(&buf)
+	if err := enc.Encode(map[string]any{"a": 1, "b": 2, "c": 3}); err != nil {
+		t.Fatal(err)
+	}
+	// Fewer keys than the header, one of them not in it.
+	err := enc.Encode(map[string]any{"a": 4, "x": 5})
+	if err == nil || !strings.Contains(err.Error(), `"x"`) {
+		t.Fatalf("Encode = %v, want an error naming column x", err)
+	}
+	// Missing keys alone are empty fields.
+	if err := enc.Encode(map[string]any{"b": 6}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "a,b,c\n1,2,3\n,6,\n"; got != want {
+		t.Fatalf("wrote %q, want %q", got, want)
+	}
+}
+
+func TestGobCodecJSONNumber(t *testing.T) {
+	src := NewJSONDecoder(strings.NewReader(`[{"id": 12345678901234567890}]`))
+	src.UseNumber()
+	var buf bytes.Buffer
+	dst := Gob.NewEncoder(&buf)
+	if n, err := Convert(dst, src, nil); err != nil || n != 1 {
+		t.Fatalf("Convert = %d, %v", n, err)
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := Gob.NewDecoder(&buf)
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m["id"]; got != json.Number("12345678901234567890") {
+		t.Fatalf("id = %#v, want the json.Number", got)
+	}
+	if err := dec.Decode(&m); err != io.EOF {
+		t.Fatalf("Decode after the last record = %v, want EOF", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want Codec
+	}{
+		{"json", JSON},
+		{"NDJSON", NDJSON},
+		{"Csv", CSV},
+		{"binary", Binary},
+		{"gob", Gob},
+		{"yaml", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		c, ok := Lookup(tt.name)
+		if c != tt.want || ok != (tt.want != nil) {
+			t.Errorf("Lookup(%q) = %v, %v, want %v", tt.name, c, ok, tt.want)
+		}
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Codec
+	}{
+		{"application/json", JSON},
+		{"Application/JSON; charset=utf-8", JSON},
+		{"application/x-ndjson", NDJSON},
+		{"text/csv; charset=utf-8; header=present", CSV},
+		{"TEXT/CSV", CSV},
+		{"application/x-gob", Gob},
+		{"application/x-binary-record", Binary},
+		{"text/plain", nil},
+		{"text/csv; charset", nil}, // malformed parameters
+		{"", nil},
+	}
+	for _, tt := range tests {
+		c, ok := ForContentType(tt.contentType)
+		if c != tt.want || ok != (tt.want != nil) {
+			t.Errorf("ForContentType(%q) = %v, %v, want %v", tt.contentType, c, ok, tt.want)
+		}
+	}
+}
+
+func TestForFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want Codec
+	}{
+		{".csv", CSV},
+		{"csv", CSV},
+		{"JSONL", NDJSON},
+		{"data.json", JSON},
+		{"dir/data.NDJSON", NDJSON},
+		{"/tmp/a.b/records.bin", Binary},
+		{"archive.gob", Gob},
+		{"dir/csv", nil}, // a path without an extension
+		{"notes.txt", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		c, ok := ForFile(tt.name)
+		if c != tt.want || ok != (tt.want != nil) {
+			t.Errorf("ForFile(%q) = %v, %v, want %v", tt.name, c, ok, tt.want)
+		}
+	}
+}
+
+type testCodec struct{ jsonCodec }
+
+func TestRegister(t *testing.T) {
+	// A new codec may not take over an extension or content type.
+	c := testCodec{jsonCodec{name: "test-register", contentType: "text/csv"}}
+	Register(c, "csv", ".test-register")
+	if got, _ := ForFile("a.csv"); got != CSV {
+		t.Errorf("ForFile(a.csv) = %v after Register, want CSV", got)
+	}
+	if got, _ := ForContentType("text/csv"); got != CSV {
+		t.Errorf("ForContentType(text/csv) = %v after Register, want CSV", got)
+	}
+	if got, _ := ForFile("a.TEST-REGISTER"); got != c {
+		t.Errorf("ForFile(a.TEST-REGISTER) = %v, want the new codec", got)
+	}
+	if !slices.Contains(Codecs(), Codec(c)) {
+		t.Error("Codecs does not list the new codec")
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "test-register") {
+			t.Fatalf("second Register recovered %v, want a panic naming the codec", r)
+		}
+	}()
+	Register(testCodec{jsonCodec{name: "Test-Register"}})
+}
+
+type convRecord struct {
+	ID    int
+	Name  string
+	Score float64
+	OK    bool
+}
+
+var convRecords = []convRecord{
+	{1, "ann", 2.5, true},
+	{2, "bo, \"jr\"", -1e-3, false},
+}
+
+var builtinCodecs = []Codec{JSON, NDJSON, CSV, Binary, Gob}
+
+// TestConvertPairs converts struct records between every pair of built-in
+// codecs and decodes the result.
+func TestConvertPairs(t *testing.T) {
+	newRecord := func() any { return new(convRecord) }
+	for _, from := range builtinCodecs {
+		var src bytes.Buffer
+		enc := from.NewEncoder(&src)
+		for _, r := range convRecords {
+			if err := enc.Encode(r); err != nil {
+				t.Fatalf("%s: Encode: %v", from.Name(), err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, to := range builtinCodecs {
+			var dst bytes.Buffer
+			enc := to.NewEncoder(&dst)
+			n, err := Convert(enc, from.NewDecoder(bytes.NewReader(src.Bytes())), newRecord)
+			if err != nil || n != len(convRecords) {
+				t.Fatalf("%s to %s: Convert = %d, %v", from.Name(), to.Name(), n, err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			dec := to.NewDecoder(&dst)
+			for i, want := range convRecords {
+				var got convRecord
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("%s to %s: record %d: %v", from.Name(), to.Name(), i, err)
+				}
+				if got != want {
+					t.Fatalf("%s to %s: record %d = %+v, want %+v", from.Name(), to.Name(), i, got, want)
+				}
+			}
+			if err := dec.Decode(new(convRecord)); err != io.EOF {
+				t.Fatalf("%s to %s: Decode after the last record = %v, want EOF", from.Name(), to.Name(), err)
+			}
+		}
+	}
+}
+
+func TestConvertMapsWithBinary(t *testing.T) {
+	var b bytes.Buffer
+	_, err := Convert(Binary.NewEncoder(&b), JSON.NewDecoder(strings.NewReader(`[{"ID":1}]`)), nil)
+	if err != errConvertBinaryMap {
+		t.Errorf("Convert to Binary = %v, want %v", err, errConvertBinaryMap)
+	}
+	_, err = Convert(JSON.NewEncoder(&b), Binary.NewDecoder(bytes.NewReader([]byte{2})), nil)
+	if err != errConvertBinaryMap {
+		t.Errorf("Convert from Binary = %v, want %v", err, errConvertBinaryMap)
+	}
+}
@@ -0,0 +1,153 @@
+// Package encode implements streaming codecs for the formats used across
+// the repository: CSV, JSON and a tagged binary record format.
+//
+// Each format lives in its own file. Readers and decoders work record by
+// record on an io.Reader so that inputs larger than memory can be
+// processed; writers and encoders buffer their output and must be flushed.
+// Codec gives every format the same Encoder and Decoder interface, with a
+// registry for looking formats up by name, content type or file extension.
+package encode
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// These are the errors that can be returned in CSVParseError.Err. They are
+// the errors of encoding/csv, so errors.Is matches either name.
+var (
+	ErrCSVBareQuote  = csv.ErrBareQuote
+	ErrCSVQuote      = csv.ErrQuote
+	ErrCSVFieldCount = csv.ErrFieldCount
+)
+
+// A CSVParseError is returned for parsing errors. It is encoding/csv's
+// ParseError, which records the line the record starts on and the line and
+// column of the error; columns count bytes and are 1-indexed.
+type CSVParseError = csv.ParseError
+
+// A CSVReader reads records from a CSV-encoded stream as described in
+// RFC 4180. It is a thin wrapper around encoding/csv's Reader that keeps
+// the options as fields, like CSVWriter, so that the struct codecs in this
+// package can be configured the same way.
+//
+// Fields may be quoted with '"'; quoted fields may contain the delimiter,
+// newlines and doubled quotes, which stand for one quote. A "\r\n" line
+// ending is read as "\n", both between records and inside quoted fields.
+// Empty lines and lines starting with the Comment character are skipped.
+//
+// Fields of the record last returned by Read can be located in the input
+// with FieldPos, which is how parse errors report their position.
+type CSVReader struct {
+	// Comma is the field delimiter. It is set to ',' by NewCSVReader.
+	// It must be a valid rune that is not '"', '\r', '\n' or the
+	// Unicode replacement character.
+	Comma rune
+
+	// Comment, if not 0, is the comment character. Lines beginning with
+	// Comment, without preceding whitespace, are ignored.
+	Comment rune
+
+	// FieldsPerRecord is the number of expected fields per record.
+	// If positive, every record must have that many fields. If 0, it is
+	// set to the number of fields in the first record. If negative,
+	// records may have a variable number of fields.
+	FieldsPerRecord int
+
+	// TrimLeadingSpace, if true, ignores leading white space in a field.
+	TrimLeadingSpace bool
+
+	r *csv.Reader
+}
+
+// NewCSVReader returns a new CSVReader that reads from r.
+func NewCSVReader(r io.Reader) *CSVReader {
+	return &CSVReader{Comma: ',', r: csv.NewReader(r)}
+}
+
+// Read reads one record (a slice of fields) from r. If the record has an
+// unexpected number of fields, Read returns the record along with an error
+// wrapping ErrCSVFieldCount. If there is no data left to be read, Read
+// returns nil, io.EOF.
+func (r *CSVReader) Read() (record []string, err error) {
+	r.r.Comma = r.Comma
+	r.r.Comment = r.Comment
+	r.r.FieldsPerRecord = r.FieldsPerRecord
+	r.r.TrimLeadingSpace = r.TrimLeadingSpace
+	record, err = r.r.Read()
+	// The first record fixes FieldsPerRecord when it is zero.
+	r.FieldsPerRecord = r.r.FieldsPerRecord
+	return record, err
+}
+
+// ReadAll reads all the remaining records from r. A successful call returns
+// err == nil, not err == io.EOF.
+func (r *CSVReader) ReadAll() (records [][]string, err error) {
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// FieldPos returns the line and column where the field with the given index
+// starts in the record most recently returned by Read. Numbering of lines
+// and columns starts at 1; columns are counted in bytes.
+// It panics if field is out of range.
+func (r *CSVReader) FieldPos(field int) (line, column int) { return r.r.FieldPos(field) }
+
+// InputOffset returns the input stream byte offset of the current reader
+// position: the end of the most recently read record.
+func (r *CSVReader) InputOffset() int64 { return r.r.InputOffset() }
+
+// A CSVWriter writes records using CSV encoding as described in RFC 4180.
+// Like CSVReader, it wraps the encoding/csv implementation.
+//
+// Fields are quoted only when needed: when they contain the delimiter, a
+// quote, a line break or leading white space. Writes are buffered, so
+// Flush must eventually be called to ensure a record has reached the
+// underlying io.Writer; call Error afterwards to check for failures.
+type CSVWriter struct {
+	Comma   rune // Field delimiter (set to ',' by NewCSVWriter)
+	UseCRLF bool // True to use \r\n as the line terminator
+	w       *csv.Writer
+}
+
+// NewCSVWriter returns a new CSVWriter that writes to w.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{Comma: ',', w: csv.NewWriter(w)}
+}
+
+// Write writes a single CSV record to w along with any necessary quoting.
+// A record is a slice of strings with each string being one field.
+// Writes are buffered, so Flush must eventually be called.
+func (w *CSVWriter) Write(record []string) error {
+	w.w.Comma = w.Comma
+	w.w.UseCRLF = w.UseCRLF
+	return w.w.Write(record)
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+// To check if an error occurred during Flush, call Error.
+func (w *CSVWriter) Flush() { w.w.Flush() }
+
+// Error reports any error that has occurred during
+// a previous Write or Flush.
+func (w *CSVWriter) Error() error { return w.w.Error() }
+
+// WriteAll writes multiple CSV records to w using Write and
+// then calls Flush, returning any error from the Flush.
+func (w *CSVWriter) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
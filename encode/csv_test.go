@@ -0,0 +1,138 @@
+package encode
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rfc4180 is a conformance corpus for CSVReader, one case per rule of
+// RFC 4180 section 2 plus the extensions CSVReader documents.
+var rfc4180 = []struct {
+	name    string
+	in      string
+	want    [][]string
+	comma   rune
+	comment rune
+	fields  int
+	err     error // wrapped by a *CSVParseError
+	line    int   // line of the parse error
+	col     int   // column of the parse error
+}{
+	// 2.1: records are separated by line breaks (CRLF).
+	{name: "2.1 CRLF", in: "aaa,bbb,ccc\r\nzzz,yyy,xxx\r\n", want: [][]string{{"aaa", "bbb", "ccc"}, {"zzz", "yyy", "xxx"}}},
+	{name: "2.1 LF", in: "aaa,bbb,ccc\nzzz,yyy,xxx\n", want: [][]string{{"aaa", "bbb", "ccc"}, {"zzz", "yyy", "xxx"}}},
+	// 2.2: the last record may lack a line break.
+	{name: "2.2 no final break", in: "aaa,bbb,ccc\r\nzzz,yyy,xxx", want: [][]string{{"aaa", "bbb", "ccc"}, {"zzz", "yyy", "xxx"}}},
+	// 2.3: an optional header line has the same format.
+	{name: "2.3 header", in: "field_name,field_name,field_name\r\naaa,bbb,ccc\r\n", want: [][]string{{"field_name", "field_name", "field_name"}, {"aaa", "bbb", "ccc"}}},
+	// 2.4: fields are separated by commas; spaces are part of a field; the
+	// last field is not followed by a comma.
+	{name: "2.4 spaces", in: " a , b ,c\n", want: [][]string{{" a ", " b ", "c"}}},
+	{name: "2.4 empty fields", in: ",,\n", want: [][]string{{"", "", ""}}},
+	{name: "2.4 trailing comma", in: "a,b,\n", want: [][]string{{"a", "b", ""}}},
+	{name: "2.4 field count", in: "a,b,c\nd,e\n", want: [][]string{{"a", "b", "c"}}, err: ErrCSVFieldCount, line: 2, col: 1},
+	// 2.5: fields may be quoted; quotes may not appear in unquoted fields.
+	{name: "2.5 quoted", in: `"aaa","bbb","ccc"` + "\r\n", want: [][]string{{"aaa", "bbb", "ccc"}}},
+	{name: "2.5 mixed", in: `"aaa",bbb,"ccc"` + "\n", want: [][]string{{"aaa", "bbb", "ccc"}}},
+	{name: "2.5 bare quote", in: "a,b\"c\n", err: ErrCSVBareQuote, line: 1, col: 4},
+	// 2.6: quoted fields may contain line breaks, commas and quotes.
+	{name: "2.6 line break", in: "\"aaa\",\"b\r\nbb\",\"ccc\"\r\nzzz,yyy,xxx\r\n", want: [][]string{{"aaa", "b\nbb", "ccc"}, {"zzz", "yyy", "xxx"}}},
+	{name: "2.6 comma", in: `"a,b",c` + "\n", want: [][]string{{"a,b", "c"}}},
+	{name: "2.6 blank lines inside", in: "\"a\n\n\nb\"\n", want: [][]string{{"a\n\n\nb"}}},
+	// 2.7: a quote inside a quoted field is escaped by doubling it.
+	{name: "2.7 doubled quote", in: `"aaa","b""bb","ccc"` + "\n", want: [][]string{{"aaa", `b"bb`, "ccc"}}},
+	{name: "2.7 only quotes", in: `""""` + "\n", want: [][]string{{`"`}}},
+	{name: "2.7 empty quoted", in: `"",""` + "\n", want: [][]string{{"", ""}}},
+	{name: "2.7 stray quote", in: `"a"b,c` + "\n", err: ErrCSVQuote, line: 1, col: 3},
+	{name: "2.7 unterminated", in: "a,\"b\nc\n", err: ErrCSVQuote, line: 2, col: 3},
+
+	// Extensions: delimiter, comments and empty lines.
+	{name: "semicolon", in: "a;\"b;c\"\n", comma: ';', want: [][]string{{"a", "b;c"}}},
+	{name: "tab", in: "a\tb\n", comma: '\t', want: [][]string{{"a", "b"}}},
+	{name: "multibyte comma", in: "a§b\n", comma: '§', want: [][]string{{"a", "b"}}},
+	{name: "comment", in: "#x,y\na,b\n#z\n", comment: '#', want: [][]string{{"a", "b"}}},
+	{name: "comment inside quotes", in: "\"a\n#b\"\n", comment: '#', want: [][]string{{"a\n#b"}}},
+	{name: "empty lines", in: "\n\na,b\n\n\nc,d\n", want: [][]string{{"a", "b"}, {"c", "d"}}},
+	{name: "variable fields", in: "a\nb,c\n", fields: -1, want: [][]string{{"a"}, {"b", "c"}}},
+	{name: "error on later line", in: "a,b\n\"c\nd\"e,f\n", err: ErrCSVQuote, line: 3, col: 2, want: [][]string{{"a", "b"}}},
+}
+
+func TestCSVReaderRFC4180(t *testing.T) {
+	for _, tt := range rfc4180 {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewCSVReader(strings.NewReader(tt.in))
+			if tt.comma != 0 {
+				r.Comma = tt.comma
+			}
+			r.Comment = tt.comment
+			r.FieldsPerRecord = tt.fields
+
+			var got [][]string
+			var err error
+			for {
+				var rec []string
+				rec, err = r.Read()
+				if err != nil {
+					break
+				}
+				got = append(got, rec)
+			}
+			if tt.err == nil {
+				if err != io.EOF {
+					t.Fatalf("Read error %v", err)
+				}
+			} else {
+				var pe *CSVParseError
+				if !errors.As(err, &pe) || !errors.Is(err, tt.err) {
+					t.Fatalf("Read error %v, want a parse error wrapping %v", err, tt.err)
+				}
+				if pe.Line != tt.line || pe.Column != tt.col {
+					t.Fatalf("error at %d:%d, want %d:%d (%v)", pe.Line, pe.Column, tt.line, tt.col, err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("records %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	records := [][]string{
+		{"plain", "with,comma", `with "quotes"`, "multi\nline", " leading space", ""},
+		{"crlf\r\ninside", `\.`, "§", "x", "y", "z"},
+	}
+	for _, crlf := range []bool{false, true} {
+		var b strings.Builder
+		w := NewCSVWriter(&b)
+		w.UseCRLF = crlf
+		if err := w.WriteAll(records); err != nil {
+			t.Fatal(err)
+		}
+		got, err := NewCSVReader(strings.NewReader(b.String())).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{records[0], {"crlf\ninside", `\.`, "§", "x", "y", "z"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("UseCRLF %v: read back %q, want %q\n%s", crlf, got, want, b.String())
+		}
+	}
+}
+
+func TestCSVFieldPos(t *testing.T) {
+	r := NewCSVReader(strings.NewReader("a,b\n\"c\nd\",e\n"))
+	r.Read()
+	r.Read()
+	for i, want := range [][2]int{{2, 1}, {3, 4}} {
+		if line, col := r.FieldPos(i); line != want[0] || col != want[1] {
+			t.Errorf("FieldPos(%d) = %d, %d, want %d, %d", i, line, col, want[0], want[1])
+		}
+	}
+	if off := r.InputOffset(); off != int64(len("a,b\n\"c\nd\",e\n")) {
+		t.Errorf("InputOffset = %d", off)
+	}
+}
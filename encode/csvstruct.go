@@ -0,0 +1,531 @@
+package encode
+
+import (
+	"bytes"
+	"cmp"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CSVUnmarshaler is implemented by types that can decode themselves from a
+// CSV field. It takes precedence over encoding.TextUnmarshaler.
+type CSVUnmarshaler interface {
+	UnmarshalCSV(field string) error
+}
+
+// CSVMarshaler is implemented by types that can encode themselves as a CSV
+// field. It takes precedence over encoding.TextMarshaler.
+type CSVMarshaler interface {
+	MarshalCSV() (string, error)
+}
+
+// A CSVFieldError describes a field that could not be converted to or from
+// its struct field.
+type CSVFieldError struct {
+	Line   int    // line of the field in the input; 0 when encoding
+	Column int    // column of the field in the input; 0 when encoding
+	Header string // the header of the field's column
+	Field  string // the struct field, as Type.Name
+	Err    error
+}
+
+func (e *CSVFieldError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: csv field %q into %s: %v", e.Line, e.Column, e.Header, e.Field, e.Err)
+	}
+	return fmt.Sprintf("csv field %q from %s: %v", e.Header, e.Field, e.Err)
+}
+
+func (e *CSVFieldError) Unwrap() error { return e.Err }
+
+var (
+	errCSVNotStructPtr   = errors.New("encode: CSV decode target must be a non-nil pointer to a struct")
+	errCSVNotStructSlice = errors.New("encode: CSV marshal and unmarshal need a slice of structs")
+)
+
+// A CSVDecoder decodes CSV records into structs.
+//
+// The first record is the header. Struct fields are matched to columns by
+// their `csv:"name"` tag, or by their name if they have no tag, first
+// exactly and then case-insensitively. A tag of "-" skips the field, and
+// the fields of embedded structs are promoted; when names collide, the
+// shallower field wins as in encoding/json. Columns without a matching
+// field are ignored.
+//
+// Fields are converted with the strconv parsers, reading integers in base
+// 10 so that leading zeros are kept out of octal, or with UnmarshalCSV or
+// UnmarshalText if the field's type implements CSVUnmarshaler or
+// encoding.TextUnmarshaler. An empty field sets any field that is not a
+// string or a CSVUnmarshaler to its zero value, so pointers become nil.
+type CSVDecoder struct {
+	r      *CSVReader
+	header []string
+	err    error
+}
+
+// NewCSVDecoder returns a decoder that reads records from r.
+// Configure r, for example its delimiter, before the first Decode.
+func NewCSVDecoder(r *CSVReader) *CSVDecoder {
+	return &CSVDecoder{r: r}
+}
+
+// Header returns the column names, reading the header record if it has not
+// been read yet.
+func (d *CSVDecoder) Header() ([]string, error) {
+	if d.header == nil && d.err == nil {
+		d.header, d.err = d.r.Read()
+		if d.err == nil && d.header == nil {
+			d.header = []string{}
+		}
+	}
+	return d.header, d.err
+}
+
+// Decode reads the next record into the struct pointed to by v.
+// It returns io.EOF when there are no more records.
+func (d *CSVDecoder) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errCSVNotStructPtr
+	}
+	header, err := d.Header()
+	if err != nil {
+		return err
+	}
+	record, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+
+	fields := csvFieldsOf(rv.Elem().Type())
+	for i, col := range header {
+		if i >= len(record) {
+			break
+		}
+		f, ok := fields.lookup(col)
+		if !ok {
+			continue
+		}
+		fv, err := fieldByIndex(rv.Elem(), f.index)
+		if err == nil {
+			err = setCSVField(fv, record[i])
+		}
+		if err != nil {
+			line, column := d.r.FieldPos(i)
+			return &CSVFieldError{Line: line, Column: column, Header: col, Field: rv.Elem().Type().Name() + "." + f.goName, Err: err}
+		}
+	}
+	return nil
+}
+
+// UnmarshalCSV decodes the CSV document in data, header first, into the
+// slice of structs (or struct pointers) pointed to by v.
+func UnmarshalCSV(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errCSVNotStructSlice
+	}
+	slice := rv.Elem()
+	elem := slice.Type().Elem()
+	isPtr := elem.Kind() == reflect.Pointer
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return errCSVNotStructSlice
+	}
+
+	dec := NewCSVDecoder(NewCSVReader(bytes.NewReader(data)))
+	for {
+		item := reflect.New(elem)
+		err := dec.Decode(item.Interface())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !isPtr {
+			item = item.Elem()
+		}
+		slice.Set(reflect.Append(slice, item))
+	}
+}
+
+// A CSVEncoder encodes structs as CSV records, writing a header generated
+// from the struct's fields before the first record. Fields are named and
+// selected as for CSVDecoder; a tag option of omitempty writes zero values
+// as empty fields.
+type CSVEncoder struct {
+	// NoHeader, if set before the first Encode, suppresses the header.
+	NoHeader bool
+
+	w      *CSVWriter
+	typ    reflect.Type // set by the first Encode
+	fields *csvFields
+	record []string
+}
+
+// NewCSVEncoder returns an encoder that writes records to w.
+func NewCSVEncoder(w *CSVWriter) *CSVEncoder {
+	return &CSVEncoder{w: w}
+}
+
+// Encode writes v, a struct or pointer to a struct, as a record. Every
+// value passed to an encoder must have the same type.
+func (e *CSVEncoder) Encode(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return errCSVNotStructPtr
+	}
+	if e.typ == nil {
+		if err := e.writeHeader(rv.Type()); err != nil {
+			return err
+		}
+	} else if rv.Type() != e.typ {
+		return fmt.Errorf("encode: CSV encoder got %s after %s", rv.Type(), e.typ)
+	}
+
+	for i, f := range e.fields.list {
+		s, err := formatCSVField(rv, f)
+		if err != nil {
+			return &CSVFieldError{Header: f.name, Field: e.typ.Name() + "." + f.goName, Err: err}
+		}
+		e.record[i] = s
+	}
+	return e.w.Write(e.record)
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (e *CSVEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// writeHeader fixes the encoder's struct type and writes its header
+// unless NoHeader is set.
+func (e *CSVEncoder) writeHeader(t reflect.Type) error {
+	e.typ = t
+	e.fields = csvFieldsOf(t)
+	e.record = make([]string, len(e.fields.list))
+	if e.NoHeader {
+		return nil
+	}
+	for i, f := range e.fields.list {
+		e.record[i] = f.name
+	}
+	return e.w.Write(e.record)
+}
+
+// MarshalCSV encodes v, a slice of structs or struct pointers, as a CSV
+// document with a header record. An empty slice produces just the header.
+func MarshalCSV(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, errCSVNotStructSlice
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, errCSVNotStructSlice
+	}
+
+	var buf bytes.Buffer
+	enc := NewCSVEncoder(NewCSVWriter(&buf))
+	if err := enc.writeHeader(elem); err != nil {
+		return nil, err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// csvField describes a struct field mapped to a CSV column.
+type csvField struct {
+	name      string // column name
+	goName    string
+	index     []int
+	tagged    bool // the name came from a csv tag
+	omitEmpty bool
+}
+
+type csvFields struct {
+	list   []csvField
+	byName map[string]int
+	byFold map[string]int
+}
+
+func (fs *csvFields) lookup(col string) (csvField, bool) {
+	if i, ok := fs.byName[col]; ok {
+		return fs.list[i], true
+	}
+	if i, ok := fs.byFold[strings.ToLower(col)]; ok {
+		return fs.list[i], true
+	}
+	return csvField{}, false
+}
+
+var csvFieldCache sync.Map // map[reflect.Type]*csvFields
+
+// csvFieldsOf returns the column mapping for struct type t.
+func csvFieldsOf(t reflect.Type) *csvFields {
+	if fs, ok := csvFieldCache.Load(t); ok {
+		return fs.(*csvFields)
+	}
+	fs := &csvFields{list: collectCSVFields(t), byName: make(map[string]int), byFold: make(map[string]int)}
+	for i, f := range fs.list {
+		fs.byName[f.name] = i
+		if _, dup := fs.byFold[strings.ToLower(f.name)]; !dup {
+			fs.byFold[strings.ToLower(f.name)] = i
+		}
+	}
+	actual, _ := csvFieldCache.LoadOrStore(t, fs)
+	return actual.(*csvFields)
+}
+
+// collectCSVFields returns the fields of struct type t in declaration
+// order, including those promoted from embedded structs. When several
+// fields have the same column name, the one that wins follows Go's rules
+// for selectors, with encoding/json's tie-break: the shallowest field, or
+// among equally shallow ones the only one with a tag. If that leaves a
+// tie, none of them is used.
+func collectCSVFields(t reflect.Type) []csvField {
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	var fields []csvField
+	visited := make(map[reflect.Type]bool)
+	// Walk the embedded structs breadth first, one depth at a time. A type
+	// embedded twice at the same depth is walked twice, so that its fields
+	// conflict with each other as they do in Go.
+	for next := []embedded{{t: t}}; len(next) > 0; {
+		level := next
+		next = nil
+		for _, e := range level {
+			if visited[e.t] {
+				continue
+			}
+			for i := 0; i < e.t.NumField(); i++ {
+				sf := e.t.Field(i)
+				tag := sf.Tag.Get("csv")
+				if tag == "-" {
+					continue
+				}
+				idx := append(append([]int(nil), e.index...), i)
+				if sf.Anonymous && tag == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct && !isCSVScalar(ft) {
+						next = append(next, embedded{ft, idx})
+						continue
+					}
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				f := csvField{name: name, goName: sf.Name, index: idx, tagged: name != "", omitEmpty: opts == "omitempty"}
+				if !f.tagged {
+					f.name = sf.Name
+				}
+				fields = append(fields, f)
+			}
+		}
+		for _, e := range level {
+			visited[e.t] = true
+		}
+	}
+
+	// Group the fields by name, shallowest and then tagged first, and keep
+	// each group's dominant field.
+	slices.SortStableFunc(fields, func(a, b csvField) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(a.index), len(b.index)); c != 0 {
+			return c
+		}
+		switch {
+		case a.tagged && !b.tagged:
+			return -1
+		case b.tagged && !a.tagged:
+			return 1
+		}
+		return 0
+	})
+	out := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j-i == 1 || len(fields[i].index) < len(fields[i+1].index) || fields[i].tagged != fields[i+1].tagged {
+			out = append(out, fields[i])
+		}
+		i = j
+	}
+	slices.SortFunc(out, func(a, b csvField) int { return slices.Compare(a.index, b.index) })
+	return out
+}
+
+// fieldByIndex returns the nested field of v at index for decoding,
+// allocating nil embedded struct pointers on the way. As in encoding/json,
+// a nil pointer to an unexported embedded struct cannot be allocated, so
+// its promoted fields can only be decoded if the caller has set it.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var (
+	csvUnmarshalerType  = reflect.TypeFor[CSVUnmarshaler]()
+	csvMarshalerType    = reflect.TypeFor[CSVMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// isCSVScalar reports whether t converts itself to and from a single field.
+func isCSVScalar(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(csvUnmarshalerType) || pt.Implements(textUnmarshalerType) ||
+		t.Implements(csvMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// setCSVField converts s and stores it in v.
+func setCSVField(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setCSVField(v.Elem(), s)
+	}
+	if u, ok := v.Addr().Interface().(CSVUnmarshaler); ok {
+		return u.UnmarshalCSV(s)
+	}
+	if s == "" && v.Kind() != reflect.String {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// formatCSVField converts field f of struct s to a string. Fields inside
+// nil embedded struct pointers are empty.
+func formatCSVField(s reflect.Value, f csvField) (string, error) {
+	v := s
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return "", nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if f.omitEmpty && v.IsZero() {
+		return "", nil
+	}
+	switch m := v.Interface().(type) {
+	case CSVMarshaler:
+		return m.MarshalCSV()
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if v.CanAddr() {
+		switch m := v.Addr().Interface().(type) {
+		case CSVMarshaler:
+			return m.MarshalCSV()
+		case encoding.TextMarshaler:
+			b, err := m.MarshalText()
+			return string(b), err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,140 @@
+package encode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVDecimalIntegers(t *testing.T) {
+	type row struct {
+		Zip  int    `csv:"zip"`
+		ID   uint16 `csv:"id"`
+		Code int8   `csv:"code"`
+	}
+	in := "zip,id,code\n010,08,-09\n0x10,1,1\n"
+	dec := NewCSVDecoder(NewCSVReader(strings.NewReader(in)))
+	var r row
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r != (row{10, 8, -9}) {
+		t.Fatalf("decoded %+v, want {10 8 -9}", r)
+	}
+	if err := dec.Decode(&r); err == nil {
+		t.Fatal("0x10 decoded without error")
+	}
+}
+
+type csvInner struct {
+	Name  string
+	Email string `csv:"email"`
+	Score int
+}
+
+type csvOther struct {
+	Score int
+	Extra string
+}
+
+type csvOuter struct {
+	csvInner
+	*csvOther
+	Name string // shallower than csvInner.Name
+	ID   int
+}
+
+type csvScore struct {
+	Rank  int `csv:"Score"` // beats csvInner.Score at the same depth
+	Extra string
+}
+
+type csvTagged struct {
+	csvInner
+	csvScore
+}
+
+func TestCSVFieldDominance(t *testing.T) {
+	tests := []struct {
+		v    any
+		want []string
+	}{
+		// csvInner.Score and csvOther.Score tie at depth 1, so neither is used.
+		{csvOuter{}, []string{"email", "Extra", "Name", "ID"}},
+		{csvTagged{}, []string{"Name", "email", "Score", "Extra"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, f := range csvFieldsOf(reflect.TypeOf(tt.v)).list {
+			got = append(got, f.name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%T columns %q, want %q", tt.v, got, tt.want)
+		}
+	}
+
+	// Name is the outer field, both when encoding and when decoding.
+	v := csvOuter{Name: "outer", ID: 1}
+	v.csvInner.Name = "inner"
+	data, err := MarshalCSV([]csvOuter{v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "email,Extra,Name,ID\n,,outer,1\n"; string(data) != want {
+		t.Fatalf("MarshalCSV = %q, want %q", data, want)
+	}
+	var back []csvOuter
+	if err := UnmarshalCSV([]byte("Name,ID\nx,2\n"), &back); err != nil {
+		t.Fatal(err)
+	}
+	if back[0].Name != "x" || back[0].csvInner.Name != "" {
+		t.Fatalf("decoded %+v", back[0])
+	}
+}
+
+type csvCycle struct {
+	*csvCycle
+	V int
+}
+
+func TestCSVEmbeddedCycle(t *testing.T) {
+	cols := csvFieldsOf(reflect.TypeOf(csvCycle{})).list
+	if len(cols) != 1 || cols[0].name != "V" {
+		t.Fatalf("columns %+v", cols)
+	}
+}
+
+type csvHidden struct{ A int }
+
+type csvHiddenOuter struct {
+	*csvHidden
+	B string
+}
+
+func TestCSVUnexportedEmbeddedPointer(t *testing.T) {
+	// The promoted column cannot be decoded into a nil pointer to an
+	// unexported struct; it must fail rather than panic.
+	var rows []csvHiddenOuter
+	err := UnmarshalCSV([]byte("A,B\n1,x\n"), &rows)
+	var fe *CSVFieldError
+	if !errors.As(err, &fe) || fe.Header != "A" {
+		t.Fatalf("UnmarshalCSV = %v, want a CSVFieldError for column A", err)
+	}
+
+	// Once the caller has allocated it, decoding works.
+	dec := NewCSVDecoder(NewCSVReader(strings.NewReader("A,B\n1,x\n")))
+	r := csvHiddenOuter{csvHidden: new(csvHidden)}
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.A != 1 || r.B != "x" {
+		t.Fatalf("decoded %+v, %+v", r, *r.csvHidden)
+	}
+
+	// Columns not promoted through the pointer still decode.
+	rows = nil
+	if err := UnmarshalCSV([]byte("B\nx\n"), &rows); err != nil || len(rows) != 1 || rows[0].B != "x" {
+		t.Fatalf("UnmarshalCSV = %+v, %v", rows, err)
+	}
+}
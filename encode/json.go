@@ -0,0 +1,115 @@
+package encode
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	jsonstream "github.com/ops2go/go-fundamentals/readwrite/encoding/json"
+)
+
+// A JSONDecoder reads records from JSON input in either of the two common
+// layouts: a top-level array whose elements are the records, or a stream
+// of values such as newline-delimited JSON. The layout is detected from
+// the first non-space byte. Only one record is held in memory at a time.
+type JSONDecoder struct {
+	br        *bufio.Reader
+	arr       *jsonstream.ArrayDecoder
+	dec       *json.Decoder
+	useNumber bool
+}
+
+// NewJSONDecoder returns a decoder that reads from r.
+func NewJSONDecoder(r io.Reader) *JSONDecoder {
+	return &JSONDecoder{br: bufio.NewReader(r)}
+}
+
+// UseNumber causes numbers to be decoded into interface values as
+// json.Number instead of float64, preserving them exactly.
+func (d *JSONDecoder) UseNumber() { d.useNumber = true }
+
+// Decode decodes the next record into v. It returns io.EOF at the end of
+// the input.
+func (d *JSONDecoder) Decode(v any) error {
+	if d.arr == nil && d.dec == nil {
+		if err := d.detect(); err != nil {
+			return err
+		}
+	}
+	if d.arr != nil {
+		return d.arr.Decode(v)
+	}
+	return d.dec.Decode(v)
+}
+
+// detect peeks at the first non-space byte to choose the layout.
+func (d *JSONDecoder) detect() error {
+	for {
+		c, err := d.br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		d.br.UnreadByte()
+		if c == '[' {
+			d.arr = jsonstream.NewArrayDecoder(d.br)
+			if d.useNumber {
+				d.arr.Decoder().UseNumber()
+			}
+			return nil
+		}
+		d.dec = json.NewDecoder(d.br)
+		if d.useNumber {
+			d.dec.UseNumber()
+		}
+		return nil
+	}
+}
+
+// A JSONEncoder writes records either as the elements of one JSON array or
+// as newline-delimited JSON. Output is buffered; Close finishes the
+// document and flushes it.
+type JSONEncoder struct {
+	arr   *jsonstream.ArrayEncoder
+	lines *jsonstream.LinesEncoder
+}
+
+// NewJSONEncoder returns an encoder that writes a JSON array to w.
+func NewJSONEncoder(w io.Writer) *JSONEncoder {
+	return &JSONEncoder{arr: jsonstream.NewArrayEncoder(w)}
+}
+
+// NewNDJSONEncoder returns an encoder that writes one JSON value per line
+// to w.
+func NewNDJSONEncoder(w io.Writer) *JSONEncoder {
+	return &JSONEncoder{lines: jsonstream.NewLinesEncoder(w)}
+}
+
+// SetIndent pretty-prints the array written by an encoder from
+// NewJSONEncoder. It has no effect on newline-delimited output, where
+// every value must stay on one line. It must be called before the first
+// Encode.
+func (e *JSONEncoder) SetIndent(prefix, indent string) {
+	if e.arr != nil {
+		e.arr.SetIndent(prefix, indent)
+	}
+}
+
+// Encode writes v as the next record.
+func (e *JSONEncoder) Encode(v any) error {
+	if e.arr != nil {
+		return e.arr.Encode(v)
+	}
+	return e.lines.Encode(v)
+}
+
+// Close finishes the document and flushes buffered output.
+func (e *JSONEncoder) Close() error {
+	if e.arr != nil {
+		return e.arr.Close()
+	}
+	return e.lines.Flush()
+}
@@ -0,0 +1,109 @@
+// Package ping exchanges records over network connections and measures
+// round trips. Records are framed with readwrite/encoding/binary, so any
+// stream, such as a TCP connection or a pipe, carries them intact.
+package ping
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ops2go/go-fundamentals/readwrite/encoding/binary"
+)
+
+// ErrMismatch is returned by Ping when the echoed payload differs from the
+// one sent.
+var ErrMismatch = errors.New("ping: echo does not match payload")
+
+// A Conn sends and receives framed records over a network connection.
+// Send and Receive may be called concurrently with each other, but not
+// with themselves.
+type Conn struct {
+	net.Conn
+	r *binary.Reader
+	w *binary.Writer
+}
+
+// NewConn returns a Conn that frames records on c as described by opts,
+// which may be nil for the defaults.
+func NewConn(c net.Conn, opts *binary.FrameOptions) *Conn {
+	return &Conn{Conn: c, r: binary.NewReader(c, opts), w: binary.NewWriter(c, opts)}
+}
+
+// Dial connects to the address on the named network and returns a Conn
+// framing records with opts.
+func Dial(network, address string, opts *binary.FrameOptions) (*Conn, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c, opts), nil
+}
+
+// Send writes p as one record and flushes it to the connection.
+func (c *Conn) Send(p []byte) error {
+	if err := c.w.WriteFrame(p); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// Receive returns the next record. The slice is only valid until the next
+// call. It returns io.EOF when the peer closes the connection between
+// records.
+func (c *Conn) Receive() ([]byte, error) { return c.r.ReadFrame() }
+
+// Ping sends payload, waits for it to be echoed back and returns the
+// round-trip time.
+func (c *Conn) Ping(payload []byte) (time.Duration, error) {
+	start := time.Now()
+	if err := c.Send(payload); err != nil {
+		return 0, err
+	}
+	p, err := c.Receive()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	if !bytes.Equal(p, payload) {
+		return rtt, ErrMismatch
+	}
+	return rtt, nil
+}
+
+// Echo sends every record received on c back to the peer until the peer
+// closes the connection, for which it returns nil.
+func Echo(c *Conn) error {
+	for {
+		p, err := c.Receive()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.Send(p); err != nil {
+			return err
+		}
+	}
+}
+
+// Serve accepts connections on l and echoes records on each in its own
+// goroutine. It returns the error that stops l.Accept.
+func Serve(l net.Listener, opts *binary.FrameOptions) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer c.Close()
+			Echo(NewConn(c, opts))
+		}()
+	}
+}
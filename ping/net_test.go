@@ -0,0 +1,105 @@
+package ping
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ops2go/go-fundamentals/readwrite/encoding/binary"
+)
+
+func TestPingEcho(t *testing.T) {
+	opts := &binary.FrameOptions{Prefix: binary.Fixed32, Checksum: true}
+	a, b := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- Echo(NewConn(b, opts)) }()
+
+	c := NewConn(a, opts)
+	for _, p := range [][]byte{[]byte("ping"), {}, bytes.Repeat([]byte("x"), 100_000)} {
+		if rtt, err := c.Ping(p); err != nil || rtt <= 0 {
+			t.Fatalf("Ping(%d bytes) = %v, %v", len(p), rtt, err)
+		}
+	}
+	c.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Echo = %v, want nil once the peer closes", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Echo did not return after the peer closed")
+	}
+}
+
+func TestPingMismatch(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	go func() {
+		defer b.Close()
+		peer := NewConn(b, nil)
+		p, err := peer.Receive()
+		if err != nil {
+			return
+		}
+		peer.Send(append(p, '!'))
+		// Then hang up in the middle of the next exchange.
+		peer.Receive()
+	}()
+
+	c := NewConn(a, nil)
+	if _, err := c.Ping([]byte("hello")); err != ErrMismatch {
+		t.Fatalf("Ping = %v, want %v", err, ErrMismatch)
+	}
+	if _, err := c.Ping([]byte("again")); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Ping to a closed peer = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no loopback network:", err)
+	}
+	opts := &binary.FrameOptions{Checksum: true}
+	served := make(chan error, 1)
+	go func() { served <- Serve(l, opts) }()
+
+	// Several clients at once, each on its own connection.
+	const clients = 3
+	errc := make(chan error, clients)
+	for range clients {
+		go func() {
+			c, err := Dial("tcp", l.Addr().String(), opts)
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer c.Close()
+			for range 10 {
+				if _, err := c.Ping([]byte("payload")); err != nil {
+					errc <- err
+					return
+				}
+			}
+			errc <- nil
+		}()
+	}
+	for range clients {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l.Close()
+	select {
+	case err := <-served:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Serve = %v, want %v", err, net.ErrClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the listener closed")
+	}
+}
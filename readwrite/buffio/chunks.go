@@ -0,0 +1,163 @@
+package buffio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// A Chunk is a byte range of an input that starts at the beginning of a
+// line and ends just after a line ending, or at the end of the input.
+type Chunk struct {
+	Index  int   // position of the chunk among all chunks
+	Offset int64 // offset of the first byte
+	Size   int64
+}
+
+// alignWindow is how much SplitLines reads at a time while looking for a
+// line ending.
+const alignWindow = 4 << 10
+
+// SplitLines divides the first size bytes of r into at most n chunks of
+// roughly equal size, moving each boundary forward to just after the next
+// '\n' so that no line is split. There are fewer than n chunks when lines
+// are long compared with size/n, and none for empty input. Only '\n' ends a
+// line here, so input whose lines all end in a lone '\r' is one chunk.
+func SplitLines(r io.ReaderAt, size int64, n int) ([]Chunk, error) {
+	if n < 1 {
+		n = 1
+	}
+	var chunks []Chunk
+	buf := make([]byte, alignWindow)
+	start := int64(0)
+	for i := 1; i <= n && start < size; i++ {
+		end := size
+		if i < n {
+			// Search from the byte before the target, so a target that
+			// already starts a line stays where it is.
+			target := max(size*int64(i)/int64(n)-1, start)
+			var err error
+			if end, err = nextLine(r, buf, target, size); err != nil {
+				return nil, err
+			}
+		}
+		if end > start {
+			chunks = append(chunks, Chunk{Index: len(chunks), Offset: start, Size: end - start})
+			start = end
+		}
+	}
+	return chunks, nil
+}
+
+// nextLine returns the offset just after the first '\n' at or after off,
+// or size if there is none.
+func nextLine(r io.ReaderAt, buf []byte, off, size int64) (int64, error) {
+	for off < size {
+		p := buf[:min(int64(len(buf)), size-off)]
+		n, err := r.ReadAt(p, off)
+		if i := bytes.IndexByte(p[:n], '\n'); i >= 0 {
+			return off + int64(i) + 1, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		off += int64(n)
+	}
+	return size, nil
+}
+
+// ProcessChunks splits the first size bytes of r with SplitLines into n
+// chunks, or GOMAXPROCS chunks if n is zero, and calls fn for each on its
+// own goroutine with an io.SectionReader over the chunk. It returns the
+// results in input order. If a call fails, the context passed to the
+// others is cancelled and ProcessChunks returns the first error.
+//
+// Each goroutine reads its own range, so r must support concurrent ReadAt
+// calls, as *os.File does.
+func ProcessChunks[T any](ctx context.Context, r io.ReaderAt, size int64, n int,
+	fn func(ctx context.Context, c Chunk, sr *io.SectionReader) (T, error)) ([]T, error) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	chunks, err := SplitLines(r, size, n)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	results := make([]T, len(chunks))
+	var wg sync.WaitGroup
+	for _, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := fn(ctx, c, io.NewSectionReader(r, c.Offset, c.Size))
+			if err != nil {
+				cancel(err)
+				return
+			}
+			results[c.Index] = v
+		}()
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ProcessFile is ProcessChunks over the named file.
+func ProcessFile[T any](ctx context.Context, name string, n int,
+	fn func(ctx context.Context, c Chunk, sr *io.SectionReader) (T, error)) ([]T, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ProcessChunks(ctx, f, info.Size(), n, fn)
+}
+
+// ProcessLines calls fn for every line of the named file, in parallel over
+// n chunks as for ProcessFile, and returns each chunk's result in order.
+// Within a chunk, fn is called for the lines in order with the chunk's
+// accumulator, which starts as the zero T; lines are read with a
+// LineReader, and the slice passed to fn is only valid during the call.
+// A byte order mark is skipped only at the start of the file, and chunks
+// are split at '\n' as for SplitLines.
+func ProcessLines[T any](ctx context.Context, name string, n int, fn func(acc T, line []byte) (T, error)) ([]T, error) {
+	return ProcessFile(ctx, name, n, func(ctx context.Context, c Chunk, sr *io.SectionReader) (T, error) {
+		var acc T
+		lr := NewLineReader(sr)
+		// A byte order mark can only start the file, not a later chunk.
+		lr.begun = c.Offset != 0
+		for i := 0; ; i++ {
+			// Checking every line would cost more than the work itself.
+			if i%4096 == 0 {
+				if err := ctx.Err(); err != nil {
+					return acc, err
+				}
+			}
+			line, err := lr.ReadLine()
+			if err == io.EOF {
+				return acc, nil
+			}
+			if err != nil {
+				return acc, err
+			}
+			if acc, err = fn(acc, line); err != nil {
+				return acc, err
+			}
+		}
+	})
+}
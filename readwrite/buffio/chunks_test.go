@@ -0,0 +1,231 @@
+package buffio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		n     int
+		want  []string
+	}{
+		{"empty", "", 4, nil},
+		{"one chunk", "a\nb\nc\n", 1, []string{"a\nb\nc\n"}},
+		{"zero n", "a\nb\n", 0, []string{"a\nb\n"}},
+		{"even", "aa\nbb\ncc\ndd\n", 4, []string{"aa\n", "bb\n", "cc\n", "dd\n"}},
+		{"unterminated", "aa\nbb\ncc", 3, []string{"aa\n", "bb\n", "cc"}},
+		{"long line", strings.Repeat("x", 20) + "\ny\n", 4, []string{strings.Repeat("x", 20) + "\n", "y\n"}},
+		{"no newline", "abcdef", 3, []string{"abcdef"}},
+		{"more chunks than lines", "a\nb\n", 8, []string{"a\n", "b\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := SplitLines(strings.NewReader(tt.input), int64(len(tt.input)), tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for i, c := range chunks {
+				if c.Index != i {
+					t.Errorf("chunk %d has Index %d", i, c.Index)
+				}
+				got = append(got, tt.input[c.Offset:c.Offset+c.Size])
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("chunks %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLinesCoverage(t *testing.T) {
+	input := genLines(5000)
+	for _, n := range []int{1, 2, 3, 7, 16, 100} {
+		chunks, err := SplitLines(bytes.NewReader(input), int64(len(input)), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) > n {
+			t.Fatalf("n=%d: %d chunks", n, len(chunks))
+		}
+		var off int64
+		for _, c := range chunks {
+			if c.Offset != off {
+				t.Fatalf("n=%d: chunk %d at %d, want %d", n, c.Index, c.Offset, off)
+			}
+			off += c.Size
+			if input[off-1] != '\n' {
+				t.Fatalf("n=%d: chunk %d splits a line", n, c.Index)
+			}
+		}
+		if off != int64(len(input)) {
+			t.Fatalf("n=%d: chunks cover %d of %d bytes", n, off, len(input))
+		}
+	}
+}
+
+// genLines returns n lines holding the numbers 0 to n-1, padded so that
+// line lengths vary.
+func genLines(n int) []byte {
+	var b bytes.Buffer
+	for i := range n {
+		b.WriteString(strings.Repeat(" ", i%13))
+		b.WriteString(strconv.Itoa(i))
+		if i%5 == 0 {
+			b.WriteByte('\r')
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func writeTemp(t testing.TB, data []byte) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// sumLine adds the number on line to acc.
+func sumLine(acc int, line []byte) (int, error) {
+	v, err := strconv.Atoi(string(bytes.TrimSpace(line)))
+	return acc + v, err
+}
+
+func TestProcessLines(t *testing.T) {
+	const lines = 20000
+	name := writeTemp(t, genLines(lines))
+	for _, n := range []int{0, 1, 2, 5, 32} {
+		sums, err := ProcessLines(context.Background(), name, n, sumLine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > 0 && len(sums) > n {
+			t.Fatalf("n=%d: %d results", n, len(sums))
+		}
+		total := 0
+		for _, s := range sums {
+			total += s
+		}
+		if want := lines * (lines - 1) / 2; total != want {
+			t.Fatalf("n=%d: sum %d, want %d", n, total, want)
+		}
+	}
+}
+
+// appendLine collects the lines of a chunk.
+func appendLine(acc []string, line []byte) ([]string, error) {
+	return append(acc, string(line)), nil
+}
+
+func TestProcessLinesBOM(t *testing.T) {
+	// The second chunk starts with what looks like a byte order mark.
+	name := writeTemp(t, []byte("\xef\xbb\xbfa\n\xef\xbb\xbfb\n"))
+	got, err := ProcessLines(context.Background(), name, 2, appendLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !slices.Equal(got[0], []string{"a"}) || !slices.Equal(got[1], []string{"\xef\xbb\xbfb"}) {
+		t.Fatalf("lines %q, want [[a] [\ufeffb]]", got)
+	}
+}
+
+func TestProcessLinesCROnly(t *testing.T) {
+	// Chunks only split at '\n', so this is read as one chunk.
+	name := writeTemp(t, []byte("a\rb\rc\r"))
+	got, err := ProcessLines(context.Background(), name, 3, appendLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !slices.Equal(got[0], []string{"a", "b", "c"}) {
+		t.Fatalf("lines %q, want [[a b c]]", got)
+	}
+}
+
+func TestProcessChunksOrder(t *testing.T) {
+	input := []byte("a\nb\nc\nd\n")
+	got, err := ProcessChunks(context.Background(), bytes.NewReader(input), int64(len(input)), 4,
+		func(ctx context.Context, c Chunk, sr *io.SectionReader) (string, error) {
+			p, err := io.ReadAll(sr)
+			return fmt.Sprintf("%d:%s", c.Index, p), err
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0:a\n|1:b\n|2:c\n|3:d\n"; strings.Join(got, "|") != want {
+		t.Fatalf("results %q, want %q", got, want)
+	}
+}
+
+func TestProcessChunksError(t *testing.T) {
+	input := []byte("a\nb\nc\nd\n")
+	errBad := errors.New("bad chunk")
+	_, err := ProcessChunks(context.Background(), bytes.NewReader(input), int64(len(input)), 4,
+		func(ctx context.Context, c Chunk, sr *io.SectionReader) (int, error) {
+			if c.Index == 2 {
+				return 0, errBad
+			}
+			// The others run until the failure cancels them.
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+	if err != errBad {
+		t.Fatalf("ProcessChunks = %v, want %v", err, errBad)
+	}
+}
+
+// BenchmarkProcessLines shows how ProcessLines scales with the number of
+// chunks. Each chunk runs on its own goroutine, so the speedup is bounded
+// by GOMAXPROCS and by how fast the file can be read.
+func BenchmarkProcessLines(b *testing.B) {
+	data := genLines(1 << 20)
+	name := writeTemp(b, data)
+	for _, n := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("chunks=%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				if _, err := ProcessLines(context.Background(), name, n, sumLine); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSequential is the single-goroutine baseline for
+// BenchmarkProcessLines.
+func BenchmarkSequential(b *testing.B) {
+	data := genLines(1 << 20)
+	name := writeTemp(b, data)
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		f, err := os.Open(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		acc := 0
+		for line, err := range NewLineReader(f).Lines() {
+			if err != nil {
+				b.Fatal(err)
+			}
+			if acc, err = sumLine(acc, line); err != nil {
+				b.Fatal(err)
+			}
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,141 @@
+// Package buffio provides buffered readers for text input that
+// bufio.Scanner handles poorly, such as logs with very long lines.
+package buffio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// A LineTooLongError reports a line longer than LineReader.MaxLen. The
+// line is skipped, so reading can continue with the next one.
+type LineTooLongError struct {
+	Line   int   // 1-based line number
+	Offset int64 // byte offset of the start of the line
+	Len    int64 // length of the line
+	Max    int
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("line %d (offset %d): length %d exceeds limit %d", e.Line, e.Offset, e.Len, e.Max)
+}
+
+// A LineReader reads lines of any length. Lines may end in "\n", "\r\n"
+// or a lone "\r"; the line ending is removed. A UTF-8 byte order mark at
+// the start of the input is skipped. Unlike bufio.Scanner, there is no
+// limit on the length of a line unless MaxLen is set.
+type LineReader struct {
+	r *bufio.Reader
+	// MaxLen is the longest line accepted, in bytes. Zero means no limit.
+	MaxLen int
+
+	buf     []byte
+	line    int   // number of the last line returned
+	start   int64 // offset of the last line returned
+	off     int64 // bytes consumed so far
+	begun   bool  // the byte order mark has been checked
+	afterCR bool  // the last line ended in '\r', so a '\n' may follow
+}
+
+// NewLineReader returns a LineReader reading from r.
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{r: bufio.NewReader(r)}
+}
+
+var bom = []byte("\xef\xbb\xbf")
+
+// ReadLine returns the next line without its line ending. The slice is
+// only valid until the next call. A last line without a line ending is
+// returned as any other; after it ReadLine returns io.EOF. A line longer
+// than MaxLen is skipped and reported as a *LineTooLongError.
+func (l *LineReader) ReadLine() ([]byte, error) {
+	if !l.begun {
+		l.begun = true
+		if p, _ := l.r.Peek(len(bom)); bytes.Equal(p, bom) {
+			l.discard(len(bom))
+		}
+	}
+	if l.afterCR {
+		l.afterCR = false
+		if p, _ := l.r.Peek(1); len(p) == 1 && p[0] == '\n' {
+			l.discard(1)
+		}
+	}
+
+	l.buf = l.buf[:0]
+	l.start = l.off
+	var n int64 // length of the line, counting bytes not kept
+	for {
+		if l.r.Buffered() == 0 {
+			if _, err := l.r.Peek(1); err != nil {
+				if err == io.EOF && n > 0 {
+					// A last line without a line ending.
+					return l.finish(n)
+				}
+				return nil, err
+			}
+		}
+		p, _ := l.r.Peek(l.r.Buffered())
+		i := bytes.IndexAny(p, "\r\n")
+		end := i
+		if i < 0 {
+			end = len(p)
+		}
+		if l.MaxLen <= 0 || n+int64(end) <= int64(l.MaxLen) {
+			l.buf = append(l.buf, p[:end]...)
+		}
+		n += int64(end)
+		if i < 0 {
+			l.discard(len(p))
+			continue
+		}
+		l.afterCR = p[i] == '\r'
+		l.discard(i + 1)
+		return l.finish(n)
+	}
+}
+
+// finish counts the line of length n that has just been read.
+func (l *LineReader) finish(n int64) ([]byte, error) {
+	l.line++
+	if l.MaxLen > 0 && n > int64(l.MaxLen) {
+		return nil, &LineTooLongError{Line: l.line, Offset: l.start, Len: n, Max: l.MaxLen}
+	}
+	return l.buf, nil
+}
+
+func (l *LineReader) discard(n int) {
+	l.r.Discard(n)
+	l.off += int64(n)
+}
+
+// Line returns the 1-based number of the line last returned.
+func (l *LineReader) Line() int { return l.line }
+
+// Offset returns the byte offset in the input of the start of the line
+// last returned, for seeking back to it in a large file.
+func (l *LineReader) Offset() int64 { return l.start }
+
+// Lines returns an iterator over the remaining lines. Line and Offset
+// describe the current line during iteration. Iteration stops after the
+// first error other than a *LineTooLongError, which is yielded with a nil
+// line.
+func (l *LineReader) Lines() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			line, err := l.ReadLine()
+			if err == io.EOF {
+				return
+			}
+			if !yield(line, err) {
+				return
+			}
+			if _, long := err.(*LineTooLongError); err != nil && !long {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package buffio
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// A wantLine is a line, or with tooLong a skipped one, and where it starts.
+type wantLine struct {
+	text    string
+	line    int
+	offset  int64
+	tooLong bool
+}
+
+func TestReadLine(t *testing.T) {
+	long := strings.Repeat("0123456789abcdef", 5000) // 80000 bytes, over 64 KiB
+	tests := []struct {
+		name   string
+		input  string
+		maxLen int
+		want   []wantLine
+	}{
+		{"empty", "", 0, nil},
+		{"lf", "a\nbc\n", 0, []wantLine{{"a", 1, 0, false}, {"bc", 2, 2, false}}},
+		{"crlf", "a\r\nbc\r\n", 0, []wantLine{{"a", 1, 0, false}, {"bc", 2, 3, false}}},
+		{"cr", "a\rbc\rd", 0, []wantLine{{"a", 1, 0, false}, {"bc", 2, 2, false}, {"d", 3, 5, false}}},
+		{"mixed", "a\r\r\nb\n\rc", 0, []wantLine{{"a", 1, 0, false}, {"", 2, 2, false}, {"b", 3, 4, false}, {"", 4, 6, false}, {"c", 5, 7, false}}},
+		{"blank lines", "\n\n", 0, []wantLine{{"", 1, 0, false}, {"", 2, 1, false}}},
+		{"no final newline", "abc", 0, []wantLine{{"abc", 1, 0, false}}},
+		{"bom", "\xef\xbb\xbfa\r\nb", 0, []wantLine{{"a", 1, 3, false}, {"b", 2, 6, false}}},
+		{"bom only", "\xef\xbb\xbf", 0, nil},
+		{"bom not at start", "a\n\xef\xbb\xbfb", 0, []wantLine{{"a", 1, 0, false}, {"\xef\xbb\xbfb", 2, 2, false}}},
+		{"partial bom", "\xef\xbbx\n", 0, []wantLine{{"\xef\xbbx", 1, 0, false}}},
+		{"long line", long + "\nend", 0, []wantLine{{long, 1, 0, false}, {"end", 2, int64(len(long)) + 1, false}}},
+		{"max len", "abc\nabcd\r\nxy\n", 3, []wantLine{{"abc", 1, 0, false}, {"", 2, 4, true}, {"xy", 3, 10, false}}},
+		{"max len last line", "ab\nabcd", 3, []wantLine{{"ab", 1, 0, false}, {"", 2, 3, true}}},
+		{"max len long line", "x\n" + long + "\ny", 100, []wantLine{{"x", 1, 0, false}, {"", 2, 2, true}, {"y", 3, int64(len(long)) + 3, false}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, oneByte := range []bool{false, true} {
+				var r io.Reader = strings.NewReader(tt.input)
+				if oneByte {
+					r = iotest.OneByteReader(r)
+				}
+				l := NewLineReader(r)
+				l.MaxLen = tt.maxLen
+				checkLines(t, l, tt.maxLen, tt.want)
+			}
+		})
+	}
+}
+
+func checkLines(t *testing.T, l *LineReader, maxLen int, want []wantLine) {
+	t.Helper()
+	for _, w := range want {
+		got, err := l.ReadLine()
+		if w.tooLong {
+			var e *LineTooLongError
+			if !errors.As(err, &e) {
+				t.Fatalf("line %d: err = %v, want a *LineTooLongError", w.line, err)
+			}
+			if e.Line != w.line || e.Offset != w.offset || e.Max != maxLen || e.Len <= int64(maxLen) {
+				t.Fatalf("line %d: %+v", w.line, *e)
+			}
+		} else if err != nil || string(got) != w.text {
+			t.Fatalf("line %d: ReadLine = %.40q, %v, want %.40q", w.line, got, err, w.text)
+		}
+		if l.Line() != w.line || l.Offset() != w.offset {
+			t.Fatalf("line %d: Line, Offset = %d, %d, want %d, %d", w.line, l.Line(), l.Offset(), w.line, w.offset)
+		}
+	}
+	for range 2 {
+		if got, err := l.ReadLine(); err != io.EOF {
+			t.Fatalf("ReadLine at end = %q, %v, want EOF", got, err)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	l := NewLineReader(strings.NewReader("a\ntoo long\nb\nc\n"))
+	l.MaxLen = 3
+	var got []string
+	var tooLong int
+	for line, err := range l.Lines() {
+		var e *LineTooLongError
+		switch {
+		case errors.As(err, &e):
+			tooLong++
+			continue
+		case err != nil:
+			t.Fatal(err)
+		}
+		got = append(got, string(line))
+		if string(line) == "b" {
+			break
+		}
+	}
+	if strings.Join(got, ",") != "a,b" || tooLong != 1 {
+		t.Fatalf("lines %q and %d too long, want [a b] and 1", got, tooLong)
+	}
+	// Breaking out leaves the rest to read.
+	if line, err := l.ReadLine(); string(line) != "c" || err != nil {
+		t.Fatalf("ReadLine after break = %q, %v", line, err)
+	}
+}
+
+func TestLinesReadError(t *testing.T) {
+	errBoom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader("a\nb"), iotest.ErrReader(errBoom))
+	var got []string
+	var errs []error
+	for line, err := range NewLineReader(r).Lines() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		got = append(got, string(line))
+	}
+	if strings.Join(got, ",") != "a" || len(errs) != 1 || errs[0] != errBoom {
+		t.Fatalf("lines %q, errors %v, want [a] and %v", got, errs, errBoom)
+	}
+}
@@ -0,0 +1,10 @@
+// Package binary implements wire formats on top of encoding/binary, as
+// described in the README in this directory.
+//
+// Reader and Writer split a byte stream into length-prefixed frames, so
+// that records can be sent over pipes and network connections. Encoder,
+// Decoder, Marshal and Unmarshal implement a tagged field format for the
+// records themselves, in which every field carries its number and wire
+// type so that readers and writers of different schema versions can read
+// each other's records.
+package binary
@@ -0,0 +1,230 @@
+package binary
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// A Prefix selects how the length of a frame is written.
+type Prefix int
+
+const (
+	Uvarint Prefix = iota // variable length, 1 to 10 bytes
+	Fixed16               // two bytes
+	Fixed32               // four bytes
+	Fixed64               // eight bytes
+)
+
+// size returns the encoded size of a fixed prefix, or 0 for Uvarint.
+func (p Prefix) size() int {
+	switch p {
+	case Fixed16:
+		return 2
+	case Fixed32:
+		return 4
+	case Fixed64:
+		return 8
+	}
+	return 0
+}
+
+// DefaultMaxFrameSize is the largest payload accepted when
+// FrameOptions.MaxSize is zero.
+const DefaultMaxFrameSize = 16 << 20
+
+var (
+	// ErrFrameTooLarge is returned for a frame whose payload exceeds the
+	// maximum size. A Reader cannot continue after it, since the frame's
+	// bytes are not consumed.
+	ErrFrameTooLarge = errors.New("binary: frame too large")
+	// ErrChecksum is returned when a frame's CRC32 does not match its
+	// payload.
+	ErrChecksum = errors.New("binary: frame checksum mismatch")
+)
+
+// FrameOptions configures the framing shared by a Reader and a Writer.
+// Both ends of a stream must use the same options. The zero value, and a
+// nil *FrameOptions, means uvarint prefixes with no checksum.
+//
+// A frame is the length of the payload, the payload, and, if Checksum is
+// set, the CRC-32 (Castagnoli) of the payload in four bytes.
+type FrameOptions struct {
+	Prefix   Prefix
+	Order    binary.ByteOrder // for fixed prefixes and checksums; nil means big-endian
+	MaxSize  int              // largest payload; zero means DefaultMaxFrameSize
+	Checksum bool
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// normalize returns a copy of o with defaults filled in.
+func (o *FrameOptions) normalize() FrameOptions {
+	var n FrameOptions
+	if o != nil {
+		n = *o
+	}
+	if n.Order == nil {
+		n.Order = binary.BigEndian
+	}
+	if n.MaxSize <= 0 {
+		n.MaxSize = DefaultMaxFrameSize
+	}
+	if s := n.Prefix.size(); s > 0 && s < 8 && n.MaxSize >= 1<<(8*s) {
+		n.MaxSize = 1<<(8*s) - 1
+	}
+	return n
+}
+
+// A Writer writes frames to an underlying writer. Output is buffered;
+// call Flush to send it, for example after each request on a connection.
+type Writer struct {
+	w    *bufio.Writer
+	opts FrameOptions
+	hdr  [binary.MaxVarintLen64]byte
+}
+
+// NewWriter returns a Writer that writes frames to w.
+func NewWriter(w io.Writer, opts *FrameOptions) *Writer {
+	return &Writer{w: bufio.NewWriter(w), opts: opts.normalize()}
+}
+
+// WriteFrame writes p as one frame.
+func (w *Writer) WriteFrame(p []byte) error {
+	if len(p) > w.opts.MaxSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(p))
+	}
+	var hdr []byte
+	switch w.opts.Prefix {
+	case Fixed16:
+		hdr = w.hdr[:2]
+		w.opts.Order.PutUint16(hdr, uint16(len(p)))
+	case Fixed32:
+		hdr = w.hdr[:4]
+		w.opts.Order.PutUint32(hdr, uint32(len(p)))
+	case Fixed64:
+		hdr = w.hdr[:8]
+		w.opts.Order.PutUint64(hdr, uint64(len(p)))
+	default:
+		hdr = w.hdr[:binary.PutUvarint(w.hdr[:], uint64(len(p)))]
+	}
+	w.w.Write(hdr)
+	w.w.Write(p)
+	if w.opts.Checksum {
+		w.opts.Order.PutUint32(w.hdr[:4], crc32.Checksum(p, castagnoli))
+		w.w.Write(w.hdr[:4])
+	}
+	// Errors from the bufio.Writer are sticky, so checking the last write
+	// covers the others.
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// Write writes p as one frame, so that a Writer can be used as an
+// io.Writer where each call is a record.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.WriteFrame(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered frames to the underlying writer.
+func (w *Writer) Flush() error { return w.w.Flush() }
+
+// A Reader reads frames from an underlying reader.
+type Reader struct {
+	r    *bufio.Reader
+	opts FrameOptions
+	buf  []byte
+	err  error
+}
+
+// NewReader returns a Reader that reads frames from r.
+func NewReader(r io.Reader, opts *FrameOptions) *Reader {
+	return &Reader{r: bufio.NewReader(r), opts: opts.normalize()}
+}
+
+// ReadFrame returns the payload of the next frame. The slice is only
+// valid until the next call. ReadFrame returns io.EOF if the stream ends
+// between frames and io.ErrUnexpectedEOF if it ends inside one. After
+// ErrFrameTooLarge or a read error, every call returns the same error;
+// after ErrChecksum the next frame can still be read.
+func (r *Reader) ReadFrame() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	n, err := r.readLen()
+	if err != nil {
+		r.err = err
+		return nil, err
+	}
+	if n > uint64(r.opts.MaxSize) {
+		r.err = fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, n)
+		return nil, r.err
+	}
+
+	size := int(n)
+	if r.opts.Checksum {
+		size += 4
+	}
+	// Read in chunks that double in size, so that a corrupt length
+	// allocates in proportion to the input actually there.
+	buf := r.buf[:0]
+	for len(buf) < size {
+		m := min(size-len(buf), max(len(buf), r.r.Size()))
+		buf = slices.Grow(buf, m)
+		k, err := io.ReadFull(r.r, buf[len(buf):len(buf)+m])
+		buf = buf[:len(buf)+k]
+		if err != nil {
+			r.buf = buf
+			r.err = unexpectedEOF(err)
+			return nil, r.err
+		}
+	}
+	r.buf = buf
+	p := buf[:n]
+	if r.opts.Checksum && r.opts.Order.Uint32(buf[n:]) != crc32.Checksum(p, castagnoli) {
+		return nil, ErrChecksum
+	}
+	return p, nil
+}
+
+// readLen reads a length prefix. It returns io.EOF only if the stream
+// ends before the prefix starts.
+func (r *Reader) readLen() (uint64, error) {
+	s := r.opts.Prefix.size()
+	if s == 0 {
+		n, err := binary.ReadUvarint(r.r)
+		if err != nil && err != io.EOF {
+			err = unexpectedEOF(err)
+		}
+		return n, err
+	}
+	p, err := r.r.Peek(s)
+	if err != nil {
+		if err == io.EOF && len(p) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	r.r.Discard(s)
+	switch r.opts.Prefix {
+	case Fixed16:
+		return uint64(r.opts.Order.Uint16(p)), nil
+	case Fixed32:
+		return uint64(r.opts.Order.Uint32(p)), nil
+	}
+	return r.opts.Order.Uint64(p), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+)
+
+var prefixes = []Prefix{Uvarint, Fixed16, Fixed32, Fixed64}
+
+func TestFrameRoundTrip(t *testing.T) {
+	payloads := [][]byte{{}, []byte("a"), bytes.Repeat([]byte("xyz"), 100), bytes.Repeat([]byte{0, 1, 2, 3, 4}, 13000)}
+	for _, prefix := range prefixes {
+		for _, order := range []binary.ByteOrder{nil, binary.LittleEndian} {
+			for _, checksum := range []bool{false, true} {
+				opts := &FrameOptions{Prefix: prefix, Order: order, Checksum: checksum}
+				t.Run(fmt.Sprintf("prefix=%d/order=%v/checksum=%v", prefix, order, checksum), func(t *testing.T) {
+					testRoundTrip(t, opts, payloads)
+				})
+			}
+		}
+	}
+}
+
+// testRoundTrip writes payloads to one end of a pipe and reads them from
+// the other.
+func testRoundTrip(t *testing.T, opts *FrameOptions, payloads [][]byte) {
+	a, b := net.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		defer a.Close()
+		w := NewWriter(a, opts)
+		for _, p := range payloads {
+			if err := w.WriteFrame(p); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- w.Flush()
+	}()
+
+	r := NewReader(b, opts)
+	for i, want := range payloads {
+		got, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("frame %d: got %d bytes, want %d", i, len(got), len(want))
+		}
+	}
+	if _, err := r.ReadFrame(); err != io.EOF {
+		t.Fatalf("ReadFrame at end = %v, want EOF", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFramePrefixBytes(t *testing.T) {
+	crc := crc32.Checksum([]byte("hi"), crc32.MakeTable(crc32.Castagnoli))
+	tests := []struct {
+		opts FrameOptions
+		want []byte
+	}{
+		{FrameOptions{}, []byte{0x02, 'h', 'i'}},
+		{FrameOptions{Prefix: Fixed16}, []byte{0x00, 0x02, 'h', 'i'}},
+		{FrameOptions{Prefix: Fixed16, Order: binary.LittleEndian}, []byte{0x02, 0x00, 'h', 'i'}},
+		{FrameOptions{Prefix: Fixed32}, []byte{0, 0, 0, 0x02, 'h', 'i'}},
+		{FrameOptions{Prefix: Fixed64, Order: binary.LittleEndian}, []byte{0x02, 0, 0, 0, 0, 0, 0, 0, 'h', 'i'}},
+		{FrameOptions{Checksum: true}, binary.BigEndian.AppendUint32([]byte{0x02, 'h', 'i'}, crc)},
+		{FrameOptions{Prefix: Fixed16, Order: binary.LittleEndian, Checksum: true}, binary.LittleEndian.AppendUint32([]byte{0x02, 0x00, 'h', 'i'}, crc)},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		w := NewWriter(&b, &tt.opts)
+		if err := w.WriteFrame([]byte("hi")); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		if !bytes.Equal(b.Bytes(), tt.want) {
+			t.Errorf("%+v: frame % x, want % x", tt.opts, b.Bytes(), tt.want)
+		}
+	}
+}
+
+func TestFrameChecksumMismatch(t *testing.T) {
+	opts := &FrameOptions{Checksum: true}
+	var b bytes.Buffer
+	w := NewWriter(&b, opts)
+	w.WriteFrame([]byte("first"))
+	w.WriteFrame([]byte("second"))
+	w.Flush()
+	b.Bytes()[2] ^= 0xff // corrupt the first payload
+
+	r := NewReader(&b, opts)
+	if _, err := r.ReadFrame(); err != ErrChecksum {
+		t.Fatalf("ReadFrame = %v, want %v", err, ErrChecksum)
+	}
+	// The stream is still in sync.
+	if p, err := r.ReadFrame(); err != nil || string(p) != "second" {
+		t.Fatalf("ReadFrame after mismatch = %q, %v", p, err)
+	}
+}
+
+func TestFrameMaxSize(t *testing.T) {
+	small := &FrameOptions{MaxSize: 4}
+	if err := NewWriter(io.Discard, small).WriteFrame([]byte("12345")); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("WriteFrame = %v, want %v", err, ErrFrameTooLarge)
+	}
+	// A Fixed16 prefix cannot hold more than 65535, whatever MaxSize says.
+	if err := NewWriter(io.Discard, &FrameOptions{Prefix: Fixed16}).WriteFrame(make([]byte, 1<<16)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("WriteFrame with Fixed16 = %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	var b bytes.Buffer
+	w := NewWriter(&b, nil)
+	w.WriteFrame([]byte("1234"))
+	w.WriteFrame([]byte("12345"))
+	w.WriteFrame([]byte("1"))
+	w.Flush()
+	r := NewReader(&b, small)
+	if p, err := r.ReadFrame(); err != nil || string(p) != "1234" {
+		t.Fatalf("ReadFrame = %q, %v", p, err)
+	}
+	// The error is sticky, since the frame's bytes are not consumed.
+	for range 2 {
+		if _, err := r.ReadFrame(); !errors.Is(err, ErrFrameTooLarge) {
+			t.Fatalf("ReadFrame = %v, want %v", err, ErrFrameTooLarge)
+		}
+	}
+}
+
+func TestFrameTruncated(t *testing.T) {
+	for _, prefix := range prefixes {
+		opts := &FrameOptions{Prefix: prefix, Checksum: true}
+		var b bytes.Buffer
+		w := NewWriter(&b, opts)
+		w.WriteFrame([]byte("payload"))
+		w.Flush()
+		frame := b.Bytes()
+
+		for n := range len(frame) {
+			want := io.ErrUnexpectedEOF
+			if n == 0 {
+				want = io.EOF
+			}
+			r := NewReader(bytes.NewReader(frame[:n]), opts)
+			if _, err := r.ReadFrame(); err != want {
+				t.Errorf("prefix %d: %d of %d bytes: ReadFrame = %v, want %v", prefix, n, len(frame), err, want)
+			}
+		}
+	}
+}
+
+func TestFrameCorruptLength(t *testing.T) {
+	// A length just under DefaultMaxFrameSize with no payload after it.
+	in := binary.AppendUvarint(nil, DefaultMaxFrameSize-1)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewReader(bytes.NewReader(in), nil).ReadFrame()
+	runtime.ReadMemStats(&after)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("ReadFrame = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Fatalf("ReadFrame allocated %d bytes for a truncated frame", n)
+	}
+}
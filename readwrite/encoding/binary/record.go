@@ -0,0 +1,536 @@
+package binary
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A WireType tells a reader how to find the end of a field's value, so that
+// it can skip fields it does not know.
+type WireType uint8
+
+const (
+	WireVarint  WireType = 0 // uvarint; signed integers are zig-zag encoded
+	WireFixed64 WireType = 1 // eight bytes, little-endian
+	WireBytes   WireType = 2 // uvarint length followed by the bytes
+	WireFixed32 WireType = 5 // four bytes, little-endian
+)
+
+func (t WireType) String() string {
+	switch t {
+	case WireVarint:
+		return "varint"
+	case WireFixed64:
+		return "fixed64"
+	case WireBytes:
+		return "bytes"
+	case WireFixed32:
+		return "fixed32"
+	}
+	return "WireType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// MaxFieldNumber is the largest field number a record can use.
+const MaxFieldNumber = 1<<29 - 1
+
+var (
+	// ErrWireType is returned for a field whose wire type is unknown, or
+	// does not match the struct field it decodes into.
+	ErrWireType = errors.New("binary: wrong wire type")
+	// ErrFieldNumber is returned for a field number of zero or above
+	// MaxFieldNumber.
+	ErrFieldNumber = errors.New("binary: invalid field number")
+)
+
+// An Encoder builds a record in the tagged field format: a sequence of
+// fields, each a key followed by a value, with no header or terminator.
+// The key is the uvarint of the field number shifted left by three bits
+// and or'ed with the wire type, as in Protocol Buffers.
+//
+// Because every field carries its number and enough type information to
+// be skipped, records can evolve: a reader ignores fields it does not
+// know, and leaves fields the writer did not know at their zero values.
+// Never reuse a field number for a different meaning.
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes returns the record built so far. It aliases the Encoder's buffer
+// until the next Reset.
+func (e *Encoder) Bytes() []byte { return e.buf }
+
+// Reset empties the record, keeping the buffer.
+func (e *Encoder) Reset() { e.buf = e.buf[:0] }
+
+func (e *Encoder) key(num int, t WireType) {
+	if num <= 0 || num > MaxFieldNumber {
+		panic(fmt.Sprintf("binary: field number %d out of range", num))
+	}
+	e.buf = binary.AppendUvarint(e.buf, uint64(num)<<3|uint64(t))
+}
+
+// Uint appends an unsigned integer field.
+func (e *Encoder) Uint(num int, v uint64) {
+	e.key(num, WireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// Int appends a signed integer field.
+func (e *Encoder) Int(num int, v int64) {
+	e.key(num, WireVarint)
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+// Bool appends a boolean field.
+func (e *Encoder) Bool(num int, v bool) {
+	var u uint64
+	if v {
+		u = 1
+	}
+	e.Uint(num, u)
+}
+
+// Float64 appends a float64 field.
+func (e *Encoder) Float64(num int, v float64) {
+	e.key(num, WireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// Float32 appends a float32 field.
+func (e *Encoder) Float32(num int, v float32) {
+	e.key(num, WireFixed32)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(v))
+}
+
+// String appends a string field.
+func (e *Encoder) String(num int, v string) {
+	e.key(num, WireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// Blob appends a byte slice field.
+func (e *Encoder) Blob(num int, v []byte) {
+	e.key(num, WireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// Record appends a nested record whose fields are added by build.
+func (e *Encoder) Record(num int, build func(*Encoder)) {
+	e.key(num, WireBytes)
+	// Reserve one byte for the length, which fits most nested records,
+	// and move the content if it turns out to need more.
+	at := len(e.buf)
+	e.buf = append(e.buf, 0)
+	build(e)
+	n := len(e.buf) - at - 1
+	var hdr [binary.MaxVarintLen64]byte
+	h := binary.PutUvarint(hdr[:], uint64(n))
+	if h > 1 {
+		e.buf = append(e.buf, hdr[:h-1]...)
+		copy(e.buf[at+h:], e.buf[at+1:at+1+n])
+	}
+	copy(e.buf[at:], hdr[:h])
+}
+
+// A Field is one field of a record, as returned by Decoder.Field.
+type Field struct {
+	Num  int
+	Type WireType
+	u    uint64 // value of a varint or fixed field
+	data []byte // value of a bytes field
+}
+
+// Uint returns the value of a varint field, or the bits of a fixed one.
+func (f Field) Uint() uint64 { return f.u }
+
+// Int returns the value of a varint field written by Encoder.Int.
+func (f Field) Int() int64 { return int64(f.u>>1) ^ -int64(f.u&1) }
+
+// Bool returns the value of a varint field written by Encoder.Bool.
+func (f Field) Bool() bool { return f.u != 0 }
+
+// Float64 returns the value of a fixed64 field.
+func (f Field) Float64() float64 { return math.Float64frombits(f.u) }
+
+// Float32 returns the value of a fixed32 field.
+func (f Field) Float32() float32 { return math.Float32frombits(uint32(f.u)) }
+
+// Bytes returns the value of a bytes field, which may be a string, a blob
+// or a nested record. It aliases the decoded data.
+func (f Field) Bytes() []byte { return f.data }
+
+// A Decoder reads the fields of a record one at a time:
+//
+//	d := binary.NewDecoder(data)
+//	for d.Next() {
+//		f := d.Field()
+//		switch f.Num {
+//		case 1:
+//			id = f.Uint()
+//		case 2:
+//			name = string(f.Bytes())
+//		}
+//	}
+//	if err := d.Err(); err != nil {
+//		...
+//	}
+//
+// Fields the caller does not handle are skipped simply by not handling
+// them. Decoding does not allocate.
+type Decoder struct {
+	data  []byte
+	off   int
+	field Field
+	err   error
+}
+
+// NewDecoder returns a Decoder for the record in data.
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// Next advances to the next field. It returns false at the end of the
+// record or on an error, which Err reports.
+func (d *Decoder) Next() bool {
+	if d.err != nil || d.off == len(d.data) {
+		return false
+	}
+	f, n, err := readField(d.data[d.off:])
+	if err != nil {
+		d.err = fmt.Errorf("binary: record offset %d: %w", d.off, err)
+		return false
+	}
+	d.field = f
+	d.off += n
+	return true
+}
+
+// Field returns the field read by the last call to Next.
+func (d *Decoder) Field() Field { return d.field }
+
+// Err returns the first error encountered, or nil at the end of a
+// well-formed record.
+func (d *Decoder) Err() error { return d.err }
+
+// readField reads the field at the start of p and returns it with its
+// encoded length.
+func readField(p []byte) (Field, int, error) {
+	key, n := binary.Uvarint(p)
+	if n <= 0 {
+		return Field{}, 0, io.ErrUnexpectedEOF
+	}
+	f := Field{Type: WireType(key & 7)}
+	if key>>3 == 0 || key>>3 > MaxFieldNumber {
+		return Field{}, 0, ErrFieldNumber
+	}
+	f.Num = int(key >> 3)
+	p = p[n:]
+
+	switch f.Type {
+	case WireVarint:
+		u, m := binary.Uvarint(p)
+		if m <= 0 {
+			return Field{}, 0, io.ErrUnexpectedEOF
+		}
+		f.u, n = u, n+m
+	case WireFixed64:
+		if len(p) < 8 {
+			return Field{}, 0, io.ErrUnexpectedEOF
+		}
+		f.u, n = binary.LittleEndian.Uint64(p), n+8
+	case WireFixed32:
+		if len(p) < 4 {
+			return Field{}, 0, io.ErrUnexpectedEOF
+		}
+		f.u, n = uint64(binary.LittleEndian.Uint32(p)), n+4
+	case WireBytes:
+		l, m := binary.Uvarint(p)
+		if m <= 0 || l > uint64(len(p)-m) {
+			return Field{}, 0, io.ErrUnexpectedEOF
+		}
+		f.data, n = p[m:m+int(l)], n+m+int(l)
+	default:
+		return Field{}, 0, fmt.Errorf("%w: %d", ErrWireType, f.Type)
+	}
+	return f, n, nil
+}
+
+// Marshal encodes the struct v, or the struct v points to, as a record.
+//
+// Only fields with a `wire:"N"` tag are encoded, N being the field number.
+// Integers and booleans are varints, floats are fixed64 or fixed32, and
+// strings, byte slices and nested structs are bytes fields. Fields with
+// zero values are omitted, except those of pointer type, which are
+// omitted only when nil. A slice of anything other than bytes is a
+// repeated field, written as one field per element.
+func Marshal(v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, errNotStruct
+	}
+	fs, err := recordFieldsOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	var e Encoder
+	if err := fs.encode(&e, rv); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal decodes the record in data into the struct pointed to by v,
+// following the `wire` tags described for Marshal. Fields in data without a
+// matching struct field are skipped, struct fields without a field in data
+// are left unchanged, and repeated fields are appended to.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errNotStructPtr
+	}
+	fs, err := recordFieldsOf(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	return fs.decode(data, rv.Elem())
+}
+
+var (
+	errNotStruct    = errors.New("binary: Marshal needs a struct or pointer to a struct")
+	errNotStructPtr = errors.New("binary: Unmarshal needs a non-nil pointer to a struct")
+)
+
+// recordField maps a struct field to a record field.
+type recordField struct {
+	num      int
+	index    int
+	name     string
+	typ      WireType
+	repeated bool
+}
+
+type recordFields struct {
+	list  []recordField
+	byNum map[int]int
+}
+
+type recordFieldsEntry struct {
+	fs  *recordFields
+	err error
+}
+
+var recordFieldCache sync.Map // map[reflect.Type]recordFieldsEntry
+
+// recordFieldsOf returns the field mapping for struct type t.
+func recordFieldsOf(t reflect.Type) (*recordFields, error) {
+	if e, ok := recordFieldCache.Load(t); ok {
+		return e.(recordFieldsEntry).fs, e.(recordFieldsEntry).err
+	}
+	fs, err := collectRecordFields(t)
+	e, _ := recordFieldCache.LoadOrStore(t, recordFieldsEntry{fs, err})
+	return e.(recordFieldsEntry).fs, e.(recordFieldsEntry).err
+}
+
+func collectRecordFields(t reflect.Type) (*recordFields, error) {
+	fs := &recordFields{byNum: make(map[int]int)}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("wire")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		name := t.Name() + "." + sf.Name
+		num, err := strconv.Atoi(tag)
+		if err != nil || num <= 0 || num > MaxFieldNumber {
+			return nil, fmt.Errorf("binary: field %s: invalid field number %q", name, tag)
+		}
+		if j, dup := fs.byNum[num]; dup {
+			return nil, fmt.Errorf("binary: field %s: number %d already used by %s", name, num, fs.list[j].name)
+		}
+
+		ft := sf.Type
+		repeated := ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8
+		if repeated {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		wt, ok := wireTypeOf(ft)
+		if !ok {
+			return nil, fmt.Errorf("binary: field %s: unsupported type %s", name, sf.Type)
+		}
+		fs.byNum[num] = len(fs.list)
+		fs.list = append(fs.list, recordField{num: num, index: i, name: name, typ: wt, repeated: repeated})
+	}
+	return fs, nil
+}
+
+// wireTypeOf returns the wire type values of t are written with.
+func wireTypeOf(t reflect.Type) (WireType, bool) {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return WireVarint, true
+	case reflect.Float64:
+		return WireFixed64, true
+	case reflect.Float32:
+		return WireFixed32, true
+	case reflect.String, reflect.Struct:
+		return WireBytes, true
+	case reflect.Slice:
+		return WireBytes, t.Elem().Kind() == reflect.Uint8
+	}
+	return 0, false
+}
+
+func (fs *recordFields) encode(e *Encoder, v reflect.Value) error {
+	for _, f := range fs.list {
+		fv := v.Field(f.index)
+		if !f.repeated {
+			if err := encodeValue(e, f.num, fv, fv.Kind() == reflect.Pointer); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := range fv.Len() {
+			// Elements are written even when zero, to keep their positions.
+			if err := encodeValue(e, f.num, fv.Index(i), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeValue appends v as field num, skipping a zero v unless always is set.
+func encodeValue(e *Encoder, num int, v reflect.Value, always bool) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !always && v.IsZero() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		e.Bool(num, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.Int(num, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.Uint(num, v.Uint())
+	case reflect.Float64:
+		e.Float64(num, v.Float())
+	case reflect.Float32:
+		e.Float32(num, float32(v.Float()))
+	case reflect.String:
+		e.String(num, v.String())
+	case reflect.Slice:
+		e.Blob(num, v.Bytes())
+	case reflect.Struct:
+		// Nested types are checked here rather than with their parent,
+		// which lets a struct refer to itself through a pointer.
+		fs, err := recordFieldsOf(v.Type())
+		if err != nil {
+			return err
+		}
+		e.Record(num, func(e *Encoder) { err = fs.encode(e, v) })
+		return err
+	}
+	return nil
+}
+
+func (fs *recordFields) decode(data []byte, v reflect.Value) error {
+	d := NewDecoder(data)
+	for d.Next() {
+		field := d.Field()
+		i, ok := fs.byNum[field.Num]
+		if !ok {
+			continue // added by a newer writer
+		}
+		f := &fs.list[i]
+		if field.Type != f.typ {
+			return fmt.Errorf("%w: field %s is %s, record has %s", ErrWireType, f.name, f.typ, field.Type)
+		}
+		fv := v.Field(f.index)
+		if f.repeated {
+			fv.Set(reflect.Append(fv, reflect.Zero(fv.Type().Elem())))
+			fv = fv.Index(fv.Len() - 1)
+		}
+		if err := decodeValue(field, fv); err != nil {
+			return fmt.Errorf("binary: field %s: %w", f.name, err)
+		}
+	}
+	return d.Err()
+}
+
+func decodeValue(f Field, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(f.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(f.Int()) {
+			return fmt.Errorf("value %d overflows %s", f.Int(), v.Type())
+		}
+		v.SetInt(f.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.OverflowUint(f.Uint()) {
+			return fmt.Errorf("value %d overflows %s", f.Uint(), v.Type())
+		}
+		v.SetUint(f.Uint())
+	case reflect.Float64:
+		v.SetFloat(f.Float64())
+	case reflect.Float32:
+		v.SetFloat(float64(f.Float32()))
+	case reflect.String:
+		v.SetString(string(f.Bytes()))
+	case reflect.Slice:
+		v.SetBytes(append([]byte(nil), f.Bytes()...))
+	case reflect.Struct:
+		fs, err := recordFieldsOf(v.Type())
+		if err != nil {
+			return err
+		}
+		return fs.decode(f.Bytes(), v)
+	}
+	return nil
+}
+
+// Dump returns the fields of a record in a readable form for debugging,
+// one per line as "num:type value". Bytes fields are quoted.
+func Dump(data []byte) string {
+	var b strings.Builder
+	d := NewDecoder(data)
+	for d.Next() {
+		f := d.Field()
+		fmt.Fprintf(&b, "%d:%s ", f.Num, f.Type)
+		if f.Type == WireBytes {
+			b.WriteString(strconv.Quote(string(f.Bytes())))
+		} else {
+			b.WriteString(strconv.FormatUint(f.Uint(), 10))
+		}
+		b.WriteByte('\n')
+	}
+	if err := d.Err(); err != nil {
+		fmt.Fprintf(&b, "error: %v\n", err)
+	}
+	return b.String()
+}
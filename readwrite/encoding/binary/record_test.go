@@ -0,0 +1,167 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// userV1 and userV2 are two versions of the same record. Version 2 adds
+// fields, extends the nested address and drops Nick, whose number 3 is
+// retired rather than reused.
+type userV1 struct {
+	ID      uint64     `wire:"1"`
+	Name    string     `wire:"2"`
+	Nick    string     `wire:"3"`
+	Address *addressV1 `wire:"4"`
+}
+
+type addressV1 struct {
+	City string `wire:"1"`
+}
+
+type userV2 struct {
+	ID      uint64     `wire:"1"`
+	Name    string     `wire:"2"`
+	Address *addressV2 `wire:"4"`
+	Email   string     `wire:"5"`
+	Tags    []string   `wire:"6"`
+	Score   float64    `wire:"7"`
+	Admin   bool       `wire:"8"`
+}
+
+type addressV2 struct {
+	City string `wire:"1"`
+	Zip  string `wire:"2"`
+}
+
+// v1Golden is userV1{7, "ann", "a", &addressV1{"Oslo"}} as written by the
+// first version of the format. It must keep decoding.
+var v1Golden = []byte{
+	0x08, 0x07, // 1:varint 7
+	0x12, 0x03, 'a', 'n', 'n', // 2:bytes "ann"
+	0x1a, 0x01, 'a', // 3:bytes "a"
+	0x22, 0x06, 0x0a, 0x04, 'O', 's', 'l', 'o', // 4:bytes {1:bytes "Oslo"}
+}
+
+func TestRecordGolden(t *testing.T) {
+	in := userV1{7, "ann", "a", &addressV1{"Oslo"}}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, v1Golden) {
+		t.Fatalf("Marshal = % x, want % x", b, v1Golden)
+	}
+	var out userV1
+	if err := Unmarshal(v1Golden, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("Unmarshal = %+v, want %+v", out, in)
+	}
+}
+
+func TestRecordOldToNew(t *testing.T) {
+	var u userV2
+	if err := Unmarshal(v1Golden, &u); err != nil {
+		t.Fatal(err)
+	}
+	want := userV2{ID: 7, Name: "ann", Address: &addressV2{City: "Oslo"}}
+	if !reflect.DeepEqual(u, want) {
+		t.Fatalf("v2 from v1 data = %+v, want %+v", u, want)
+	}
+}
+
+func TestRecordNewToOld(t *testing.T) {
+	in := userV2{
+		ID:      9,
+		Name:    "bo",
+		Address: &addressV2{City: "Rome", Zip: "00184"},
+		Email:   "bo@example.com",
+		Tags:    []string{"x", "", "y"},
+		Score:   1.5,
+		Admin:   true,
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var old userV1
+	if err := Unmarshal(b, &old); err != nil {
+		t.Fatal(err)
+	}
+	want := userV1{ID: 9, Name: "bo", Address: &addressV1{City: "Rome"}}
+	if !reflect.DeepEqual(old, want) {
+		t.Fatalf("v1 from v2 data = %+v, want %+v", old, want)
+	}
+
+	// An old reader that re-encodes what it understood loses the new
+	// fields, but a new reader still decodes the result.
+	b, err = Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back userV2
+	if err := Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.ID != 9 || back.Name != "bo" || back.Address.City != "Rome" || back.Email != "" {
+		t.Fatalf("v2 from re-encoded v1 = %+v", back)
+	}
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	in := userV2{
+		ID:      1 << 40,
+		Name:    strings.Repeat("n", 300), // a nested length needing two bytes
+		Address: &addressV2{City: strings.Repeat("c", 200)},
+		Tags:    []string{"", "a"},
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out userV2
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestRecordWireTypeChange(t *testing.T) {
+	// Changing a field's type in place is not a compatible change.
+	type changed struct {
+		Name uint64 `wire:"2"`
+	}
+	var c changed
+	err := Unmarshal(v1Golden, &c)
+	if !errors.Is(err, ErrWireType) {
+		t.Fatalf("Unmarshal = %v, want %v", err, ErrWireType)
+	}
+}
+
+func TestRecordTruncated(t *testing.T) {
+	// Cutting between fields leaves a shorter valid record.
+	boundaries := map[int]bool{2: true, 7: true, 10: true}
+	for n := 1; n < len(v1Golden); n++ {
+		var u userV1
+		err := Unmarshal(v1Golden[:n], &u)
+		if (err == nil) != boundaries[n] {
+			t.Errorf("Unmarshal of %d bytes = %v", n, err)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	got := Dump(v1Golden)
+	want := "1:varint 7\n2:bytes \"ann\"\n3:bytes \"a\"\n4:bytes \"\\n\\x04Oslo\"\n"
+	if got != want {
+		t.Fatalf("Dump =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,652 @@
+package json
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A DecodeError locates a decoding error in the input for a human reader.
+//
+// encoding/json reports a *json.SyntaxError or *json.UnmarshalTypeError
+// with only a byte offset, as errors/README.md describes. A DecodeError
+// turns the offset into a line and column, names the JSON field the error
+// is in, such as items[4].price, and carries the offending line with a
+// caret under the problem:
+//
+//	items[4].price (line 7, column 16): json: cannot unmarshal string into Go struct field .items.4.price of type float64
+//	      "price": "12.50"
+//	               ^
+type DecodeError struct {
+	Offset  int64  // byte offset of the problem in the input
+	Line    int    // 1-based line of Offset
+	Column  int    // 1-based column of Offset, in bytes
+	Path    string // path of the JSON value, or "" for the document root
+	Snippet string // the input line and a caret line pointing at Column
+	Err     error  // the underlying error from encoding/json
+}
+
+func (e *DecodeError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "$"
+	}
+	return fmt.Sprintf("%s (line %d, column %d): %v", path, e.Line, e.Column, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// DecodeErrors is a list of decoding errors, in input order.
+type DecodeErrors []*DecodeError
+
+func (es DecodeErrors) Error() string {
+	var b strings.Builder
+	for i, e := range es {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+func (es DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Unmarshal is json.Unmarshal with errors converted by Describe.
+func Unmarshal(data []byte, v any) error {
+	return Describe(data, json.Unmarshal(data, v))
+}
+
+// UnmarshalAll is like Unmarshal but does not stop at the first value of
+// the wrong type. It decodes everything it can into v and returns every
+// type error as DecodeErrors. A syntax error still stops decoding and is
+// returned on its own as a *DecodeError.
+func UnmarshalAll(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	var te *json.UnmarshalTypeError
+	rv := reflect.ValueOf(v)
+	if !errors.As(err, &te) || rv.Kind() != reflect.Pointer {
+		return Describe(data, err)
+	}
+
+	// Unmarshal has decoded every value it could, skipping those of the
+	// wrong type, but reports only the first of them. Walk the input once
+	// against the type of v to find the rest.
+	c := typeErrors{data: data, loc: locator{data: data}}
+	start := skipSpace(data, 0)
+	c.walk(start, valueEnd(data, start), rv.Type().Elem(), typePath{})
+	switch len(c.errs) {
+	case 0:
+		return describe(data, err)
+	case 1:
+		return c.errs[0]
+	}
+	return c.errs
+}
+
+// Describe converts a *json.SyntaxError or *json.UnmarshalTypeError for
+// data into a *DecodeError. Other errors, including nil, are returned
+// unchanged. Use it with errors from json.Unmarshal, or from a
+// json.Decoder if data holds everything the decoder has read.
+func Describe(data []byte, err error) error {
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	if !errors.As(err, &se) && !errors.As(err, &te) {
+		return err
+	}
+	return describe(data, err)
+}
+
+// describe locates a syntax or type error in data.
+func describe(data []byte, err error) *DecodeError {
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		// The decoder has consumed the offending byte, unless the input
+		// ended early.
+		if se.Offset >= int64(len(data)) && truncated(data) {
+			return describeAt(data, len(data), err)
+		}
+		return describeAt(data, int(se.Offset)-1, err)
+	}
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		// Point at the start of the value that has the wrong type.
+		if start, _, ok := valueSpan(data, int(te.Offset)-1); ok {
+			return describeAt(data, start, err)
+		}
+		return describeAt(data, int(te.Offset), err)
+	}
+	return describeAt(data, len(data), err)
+}
+
+// truncated reports whether data ends in the middle of a JSON value. A
+// SyntaxError from Unmarshal has the same offset for truncated input as for
+// a bad last byte; a Decoder tells them apart.
+func truncated(data []byte) bool {
+	err := json.NewDecoder(bytes.NewReader(data)).Decode(new(json.RawMessage))
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// describeAt builds a DecodeError for position pos in data.
+func describeAt(data []byte, pos int, err error) *DecodeError {
+	pos = max(0, min(pos, len(data)))
+	l := locator{data: data}
+	return l.describe(pos, pathAt(data, pos), err)
+}
+
+// A locator turns offsets in data into lines and columns. It remembers how
+// far it has counted, so that describing many errors in input order is a
+// single pass; an earlier offset starts the count again.
+type locator struct {
+	data      []byte
+	pos       int // offset counted up to
+	line      int // 0-based line of pos
+	lineStart int // offset of the start of that line
+}
+
+// describe builds a DecodeError for position pos, whose path is known.
+func (l *locator) describe(pos int, path string, err error) *DecodeError {
+	if pos < l.pos {
+		*l = locator{data: l.data}
+	}
+	if seg := l.data[l.pos:pos]; len(seg) > 0 {
+		if n := bytes.Count(seg, []byte("\n")); n > 0 {
+			l.line += n
+			l.lineStart = l.pos + bytes.LastIndexByte(seg, '\n') + 1
+		}
+		l.pos = pos
+	}
+	// Look no further ahead than the snippet can show.
+	end := min(len(l.data), pos+snippetWidth+1)
+	lineEnd := bytes.IndexByte(l.data[pos:end], '\n')
+	if lineEnd < 0 {
+		lineEnd = end
+	} else {
+		lineEnd += pos
+	}
+	return &DecodeError{
+		Offset:  int64(pos),
+		Line:    l.line + 1,
+		Column:  pos - l.lineStart + 1,
+		Path:    path,
+		Snippet: snippet(l.data[l.lineStart:lineEnd], pos-l.lineStart),
+		Err:     err,
+	}
+}
+
+// snippetWidth is the most bytes of a line shown in a snippet.
+const snippetWidth = 72
+
+// snippet returns line, or a window of it around col, followed by a caret
+// line pointing at col (0-based).
+func snippet(line []byte, col int) string {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	prefix, suffix := "", ""
+	if len(line) > snippetWidth {
+		start := max(0, min(col-snippetWidth/2, len(line)-snippetWidth))
+		end := start + snippetWidth
+		if start > 0 {
+			prefix = "..."
+		}
+		if end < len(line) {
+			suffix = "..."
+		}
+		line = line[start:end]
+		col -= start
+	}
+	col = min(col, len(line))
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.Write(line)
+	b.WriteString(suffix)
+	b.WriteByte('\n')
+	b.WriteString(strings.Repeat(" ", len(prefix)))
+	for _, c := range line[:col] {
+		// Keep tabs so the caret lines up in a terminal.
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte('^')
+	return b.String()
+}
+
+// A pathFrame is an open object or array while scanning for a path.
+type pathFrame struct {
+	object bool
+	key    string
+	hasKey bool // the key of the current member has been read
+	index  int
+}
+
+// pathScanner tracks the path of the value at the current position of a
+// scan over possibly invalid JSON.
+type pathScanner struct {
+	stack []pathFrame
+}
+
+func (s *pathScanner) path() string {
+	var b strings.Builder
+	for _, f := range s.stack {
+		switch {
+		case !f.object:
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(f.index))
+			b.WriteByte(']')
+		case !f.hasKey:
+			// Between members: the path is the object itself.
+			return b.String()
+		case isIdent(f.key):
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(f.key)
+		default:
+			b.WriteByte('[')
+			b.WriteString(strconv.Quote(f.key))
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// scanTo scans data up to pos. If pos falls inside a value, it returns that
+// value's extent and ok. Otherwise s describes the position reached.
+func (s *pathScanner) scanTo(data []byte, pos int) (start, end int, ok bool) {
+	for i := 0; i < len(data) && i <= pos; {
+		c := data[i]
+		switch c {
+		case ' ', '\t', '\r', '\n', ':':
+			i++
+		case '{', '[':
+			if i == pos {
+				return i, matchingClose(data, i), true
+			}
+			s.stack = append(s.stack, pathFrame{object: c == '{'})
+			i++
+		case '}', ']':
+			if i == pos {
+				// A syntax error here belongs to the pending member or element.
+				return 0, 0, false
+			}
+			if len(s.stack) > 0 {
+				s.stack = s.stack[:len(s.stack)-1]
+			}
+			i++
+		case ',':
+			if n := len(s.stack); n > 0 {
+				top := &s.stack[n-1]
+				if top.object {
+					top.hasKey = false
+				} else {
+					top.index++
+				}
+			}
+			i++
+		case '"':
+			j := stringEnd(data, i)
+			if n := len(s.stack); n > 0 && s.stack[n-1].object && !s.stack[n-1].hasKey {
+				top := &s.stack[n-1]
+				top.key, _ = strconv.Unquote(string(data[i:j]))
+				top.hasKey = true
+				if pos < j {
+					// Inside a key: report the member it names.
+					return i, j, false
+				}
+				i = j
+				continue
+			}
+			if pos < j {
+				return i, j, true
+			}
+			i = j
+		default:
+			j := i
+			for j < len(data) && !bytes.ContainsRune([]byte(" \t\r\n,:]}"), rune(data[j])) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			if pos < j {
+				return i, j, true
+			}
+			i = j
+		}
+	}
+	return 0, 0, false
+}
+
+// valueSpan returns the extent of the innermost value containing pos.
+func valueSpan(data []byte, pos int) (start, end int, ok bool) {
+	var s pathScanner
+	start, end, ok = s.scanTo(data, pos)
+	if !ok && len(s.stack) > 0 {
+		// pos is inside an object or array but not inside a member or
+		// element of it, so the container itself is the value.
+		return 0, 0, false
+	}
+	return start, end, ok
+}
+
+// pathAt returns the path of the value at pos in data.
+func pathAt(data []byte, pos int) string {
+	var s pathScanner
+	s.scanTo(data, pos)
+	return s.path()
+}
+
+// stringEnd returns the index after the string literal starting at i, or
+// len(data) if it is unterminated.
+func stringEnd(data []byte, i int) int {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(data)
+}
+
+// matchingClose returns the index after the bracket closing the object or
+// array that opens at i, or len(data) if it is unterminated.
+func matchingClose(data []byte, i int) int {
+	depth := 0
+	for j := i; j < len(data); j++ {
+		switch data[j] {
+		case '"':
+			j = stringEnd(data, j) - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(data)
+}
+
+// typeErrors collects the type errors in valid JSON input by walking it
+// alongside the Go type it is decoded into. Objects and arrays are walked
+// member by member; everything else is decoded on its own, so each byte of
+// the input is decoded once. Paths and positions are tracked along the
+// way, since errors are found in input order.
+type typeErrors struct {
+	data []byte
+	loc  locator
+	errs DecodeErrors
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// A typePath is where typeErrors is in the input: path is the JSON path as
+// DecodeError reports it, field the path as encoding/json reports it in an
+// UnmarshalTypeError, and structName the innermost struct type.
+type typePath struct {
+	path       string
+	field      []string
+	structName string
+}
+
+// member returns the path of the member key, whose struct field or map
+// entry is named name.
+func (p typePath) member(key, name string) typePath {
+	switch {
+	case !isIdent(key):
+		p.path += "[" + strconv.Quote(key) + "]"
+	case p.path == "":
+		p.path = key
+	default:
+		p.path += "." + key
+	}
+	p.field = append(p.field[:len(p.field):len(p.field)], name)
+	return p
+}
+
+// element returns the path of element i.
+func (p typePath) element(i int) typePath {
+	p.path += "[" + strconv.Itoa(i) + "]"
+	p.field = append(p.field[:len(p.field):len(p.field)], strconv.Itoa(i))
+	return p
+}
+
+// walk checks the value at data[start:end] against t.
+func (c *typeErrors) walk(start, end int, t reflect.Type, p typePath) {
+	d := t
+	for d.Kind() == reflect.Pointer {
+		d = d.Elem()
+	}
+	if !customUnmarshal(t) {
+		switch {
+		case c.data[start] == '{' && d.Kind() == reflect.Struct:
+			c.walkStruct(start, d, p)
+			return
+		case c.data[start] == '{' && d.Kind() == reflect.Map:
+			c.walkMap(start, d, p)
+			return
+		case c.data[start] == '[' && (d.Kind() == reflect.Slice || d.Kind() == reflect.Array):
+			c.walkArray(start, d, p)
+			return
+		}
+	}
+	c.decode(c.data[start:end], 0, start, t, p)
+}
+
+func (c *typeErrors) walkStruct(start int, t reflect.Type, p typePath) {
+	p.structName = t.Name()
+	items(c.data, start, func(ks, ke, vs, ve int) {
+		key, _ := strconv.Unquote(string(c.data[ks:ke]))
+		ft, name, ok := structField(t, key)
+		if !ok {
+			return // Unmarshal ignores unknown keys
+		}
+		if v := c.data[vs]; (v == '{' || v == '[') && !customUnmarshal(ft) {
+			c.walk(vs, ve, ft, p.member(key, name))
+			return
+		}
+		// Decode a scalar member through the struct itself, so that tag
+		// options such as ",string" apply as they do in Unmarshal.
+		doc := make([]byte, 0, ke-ks+ve-vs+3)
+		doc = append(doc, '{')
+		doc = append(doc, c.data[ks:ke]...)
+		doc = append(doc, ':')
+		doc = append(doc, c.data[vs:ve]...)
+		doc = append(doc, '}')
+		mp := p.member(key, name)
+		mp.field = p.field // the error names the field itself
+		c.decode(doc, ke-ks+2, vs, t, mp)
+	})
+}
+
+func (c *typeErrors) walkMap(start int, t reflect.Type, p typePath) {
+	items(c.data, start, func(ks, ke, vs, ve int) {
+		key, _ := strconv.Unquote(string(c.data[ks:ke]))
+		if t.Key().Kind() != reflect.String {
+			// Keys are converted to numbers or by UnmarshalText.
+			doc := append(append([]byte{'{'}, c.data[ks:ke]...), ":null}"...)
+			kp := p.member(key, key)
+			kp.field = p.field
+			c.decode(doc, 1, ks, t, kp)
+		}
+		c.walk(vs, ve, t.Elem(), p.member(key, key))
+	})
+}
+
+func (c *typeErrors) walkArray(start int, t reflect.Type, p typePath) {
+	i := 0
+	items(c.data, start, func(_, _, vs, ve int) {
+		// Unmarshal ignores elements beyond the length of an array.
+		if t.Kind() == reflect.Slice || i < t.Len() {
+			c.walk(vs, ve, t.Elem(), p.element(i))
+		}
+		i++
+	})
+}
+
+// decode unmarshals doc into a new t and records any type error at offset
+// start of the input, where byte docStart of doc comes from.
+func (c *typeErrors) decode(doc []byte, docStart, start int, t reflect.Type, p typePath) {
+	var te *json.UnmarshalTypeError
+	if !errors.As(json.Unmarshal(doc, reflect.New(t).Interface()), &te) {
+		return
+	}
+	e := *te
+	e.Offset = int64(start) + max(e.Offset-int64(docStart), 0)
+	field := p.field
+	if e.Field != "" {
+		field = append(field[:len(field):len(field)], e.Field)
+	}
+	e.Field = strings.Join(field, ".")
+	if e.Struct == "" {
+		e.Struct = p.structName
+	}
+	c.errs = append(c.errs, c.loc.describe(start, p.path, &e))
+}
+
+// customUnmarshal reports whether values of type t, or of what it points
+// to, decode themselves.
+func customUnmarshal(t reflect.Type) bool {
+	for {
+		pt := reflect.PointerTo(t)
+		if t.Implements(jsonUnmarshalerType) || pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+			return true
+		}
+		if t.Kind() != reflect.Pointer {
+			return false
+		}
+		t = t.Elem()
+	}
+}
+
+// structField returns the type and JSON name of the field of struct type t
+// that Unmarshal stores the member key in: an exact match of the name at
+// any depth of embedding, or else a case-insensitive one.
+func structField(t reflect.Type, key string) (reflect.Type, string, bool) {
+	var foldType reflect.Type
+	var foldName string
+	visited := make(map[reflect.Type]bool)
+	for level := []reflect.Type{t}; len(level) > 0; {
+		var next []reflect.Type
+		for _, st := range level {
+			if visited[st] {
+				continue
+			}
+			visited[st] = true
+			for i := 0; i < st.NumField(); i++ {
+				sf := st.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				if sf.Anonymous && name == "" {
+					et := sf.Type
+					if et.Kind() == reflect.Pointer {
+						et = et.Elem()
+					}
+					if et.Kind() == reflect.Struct {
+						next = append(next, et)
+						continue
+					}
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				if name == key {
+					return sf.Type, name, true
+				}
+				if foldType == nil && strings.EqualFold(name, key) {
+					foldType, foldName = sf.Type, name
+				}
+			}
+		}
+		level = next
+	}
+	return foldType, foldName, foldType != nil
+}
+
+// items calls fn with the extent of each member of the object, or element
+// of the array, that starts at data[start]. For arrays ks and ke are -1;
+// for objects they are the extent of the quoted key. data must be valid.
+func items(data []byte, start int, fn func(ks, ke, vs, ve int)) {
+	i := start + 1
+	for {
+		i = skipSpace(data, i)
+		if i >= len(data) || data[i] == '}' || data[i] == ']' {
+			return
+		}
+		ks, ke := -1, -1
+		if data[start] == '{' {
+			ks, ke = i, stringEnd(data, i)
+			i = skipSpace(data, skipSpace(data, ke)+1) // past the ':'
+		}
+		ve := valueEnd(data, i)
+		fn(ks, ke, i, ve)
+		if i = skipSpace(data, ve); i < len(data) && data[i] == ',' {
+			i++
+		}
+	}
+}
+
+// valueEnd returns the index after the value that starts at data[i].
+func valueEnd(data []byte, i int) int {
+	if i >= len(data) {
+		return i
+	}
+	switch data[i] {
+	case '{', '[':
+		return matchingClose(data, i)
+	case '"':
+		return stringEnd(data, i)
+	}
+	for i < len(data) && !bytes.ContainsRune([]byte(" \t\r\n,]}"), rune(data[i])) {
+		i++
+	}
+	return i
+}
+
+// skipSpace returns the index of the first non-space byte at or after i.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\r' || data[i] == '\n') {
+		i++
+	}
+	return i
+}
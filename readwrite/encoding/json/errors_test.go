@@ -0,0 +1,148 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+	Qty   int     `json:"qty,string"`
+}
+
+type order struct {
+	ID    int               `json:"id"`
+	Items []item            `json:"items"`
+	Meta  map[string]int    `json:"meta"`
+	Codes map[int]string    `json:"codes"`
+	When  time.Time         `json:"when"`
+	Extra map[string][2]int `json:"extra"`
+}
+
+func TestDescribeSyntax(t *testing.T) {
+	tests := []struct {
+		in         string
+		line, col  int
+		path       string
+		wantCaret  string
+		wantOffset int64
+	}{
+		// Truncated input points past the end.
+		{in: "{\"items\": [1,", line: 1, col: 14, path: "items[1]", wantOffset: 13},
+		{in: "", line: 1, col: 1, wantOffset: 0},
+		// A bad last byte points at the byte, not past it.
+		{in: "[1,}", line: 1, col: 4, path: "[1]", wantOffset: 3},
+		{in: "{\"a\":\n  tru}", line: 2, col: 6, path: "a", wantOffset: 11},
+	}
+	for _, tt := range tests {
+		var v any
+		err := Unmarshal([]byte(tt.in), &v)
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Fatalf("%q: error %v is not a DecodeError", tt.in, err)
+		}
+		if de.Line != tt.line || de.Column != tt.col || de.Path != tt.path || de.Offset != tt.wantOffset {
+			t.Errorf("%q: got %d:%d %q offset %d, want %d:%d %q offset %d",
+				tt.in, de.Line, de.Column, de.Path, de.Offset, tt.line, tt.col, tt.path, tt.wantOffset)
+		}
+	}
+}
+
+func TestUnmarshalAll(t *testing.T) {
+	in := `{
+  "id": "seven",
+  "items": [
+    {"name": "a", "price": 1.5, "qty": "2"},
+    {"name": 5, "price": "12.50", "qty": "x"},
+    {"name": "c", "price": 3}
+  ],
+  "meta": {"ok": 1, "bad": "no"},
+  "codes": {"1": "one", "two": "2"},
+  "when": 12,
+  "extra": {"k": [1, "z", 3]},
+  "unknown": {"deep": [true]}
+}`
+	var o order
+	err := UnmarshalAll([]byte(in), &o)
+	var errs DecodeErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error %v is not DecodeErrors", err)
+	}
+	want := []struct {
+		path string
+		line int
+	}{
+		{"id", 2},
+		{"items[1].name", 5},
+		{"items[1].price", 5},
+		{"items[1].qty", 5},
+		{"meta.bad", 8},
+		{"codes.two", 9}, // the key is not an int
+		{"when", 10},
+		{"extra.k[1]", 11},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, w := range want {
+		if errs[i].Path != w.path || errs[i].Line != w.line {
+			t.Errorf("error %d at %s line %d, want %s line %d: %v", i, errs[i].Path, errs[i].Line, w.path, w.line, errs[i])
+		}
+	}
+	var te interface{ Unwrap() error }
+	if !errors.As(errs[2], &te) || !strings.Contains(errs[2].Error(), "Go struct field item.items.1.price of type float64") {
+		t.Errorf("error 2 = %v", errs[2])
+	}
+
+	// Everything of the right type was still decoded.
+	if len(o.Items) != 3 || o.Items[0].Qty != 2 || o.Items[1].Name != "" || o.Items[2].Price != 3 || o.Meta["ok"] != 1 || o.Codes[1] != "one" {
+		t.Errorf("decoded %+v", o)
+	}
+}
+
+func TestUnmarshalAllSingleError(t *testing.T) {
+	var o order
+	err := UnmarshalAll([]byte(`{"id": 1, "items": [{"price": true}]}`), &o)
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Path != "items[0].price" || de.Column != 31 {
+		t.Fatalf("UnmarshalAll = %#v", err)
+	}
+	if err := UnmarshalAll([]byte(`{"id": 1}`), &o); err != nil {
+		t.Fatalf("UnmarshalAll on valid input = %v", err)
+	}
+}
+
+// TestUnmarshalAllLinear checks that collecting many errors does not
+// re-decode the document for each one.
+func TestUnmarshalAllLinear(t *testing.T) {
+	run := func(n int) time.Duration {
+		var b strings.Builder
+		b.WriteString(`{"items": [`)
+		for i := range n {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `{"name": "n%d", "price": "bad"}`, i)
+		}
+		b.WriteString("]}")
+		var o order
+		start := time.Now()
+		err := UnmarshalAll([]byte(b.String()), &o)
+		elapsed := time.Since(start)
+		var errs DecodeErrors
+		if !errors.As(err, &errs) || len(errs) != n {
+			t.Fatalf("n=%d: got %d errors", n, len(errs))
+		}
+		return elapsed
+	}
+	run(1000) // warm up
+	small, large := run(2000), run(20000)
+	// Ten times the errors must cost nowhere near a hundred times as much.
+	if large > 40*small {
+		t.Fatalf("2000 errors took %v, 20000 took %v", small, large)
+	}
+}
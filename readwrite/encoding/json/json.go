@@ -0,0 +1,425 @@
+// Package json provides streaming helpers on top of encoding/json for
+// documents too large to hold in memory.
+//
+// As the READMEs in this directory describe, json.Decoder tokenizes its
+// input and decodes one value at a time. The helpers here build on that:
+// ArrayDecoder walks the elements of a huge top-level array one by one,
+// LinesDecoder reads newline-delimited JSON (NDJSON, JSON Lines), the
+// encoders write both forms incrementally, and Reformat re-indents or
+// compacts a stream token by token. Memory use depends on the size of the
+// largest single element, not of the whole input.
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// ErrNotArray is returned by ArrayDecoder when the input does not start
+// with '['.
+var ErrNotArray = errors.New("json: input is not an array")
+
+// An ArrayDecoder decodes the elements of a top-level JSON array one at a
+// time.
+type ArrayDecoder struct {
+	dec     *json.Decoder
+	started bool
+	done    bool
+}
+
+// NewArrayDecoder returns a decoder for the array read from r.
+func NewArrayDecoder(r io.Reader) *ArrayDecoder {
+	return &ArrayDecoder{dec: json.NewDecoder(r)}
+}
+
+// Decoder returns the underlying json.Decoder, for options such as
+// UseNumber or DisallowUnknownFields.
+func (d *ArrayDecoder) Decoder() *json.Decoder { return d.dec }
+
+// Decode decodes the next element of the array into v. It returns io.EOF
+// after the last element, once the closing ']' has been read; anything
+// other than white space after it is an error.
+func (d *ArrayDecoder) Decode(v any) error {
+	if !d.started {
+		tok, err := d.dec.Token()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('[') {
+			return ErrNotArray
+		}
+		d.started = true
+	}
+	if d.done {
+		return io.EOF
+	}
+	if d.dec.More() {
+		return d.dec.Decode(v)
+	}
+
+	// Consume the closing bracket and make sure nothing follows.
+	if _, err := d.dec.Token(); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	d.done = true
+	if _, err := d.dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("json: unexpected data after top-level array at offset %d", d.dec.InputOffset())
+		}
+		return err
+	}
+	return io.EOF
+}
+
+// InputOffset returns the input stream byte offset of the current decoder
+// position, which can be used to report progress.
+func (d *ArrayDecoder) InputOffset() int64 { return d.dec.InputOffset() }
+
+// Elements returns an iterator over the elements of the top-level array
+// read from r, each decoded into a new T. Iteration stops after the first
+// error, which is yielded with the zero T.
+func Elements[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		d := NewArrayDecoder(r)
+		for {
+			var v T
+			err := d.Decode(&v)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// A LineError reports an error decoding one line of NDJSON input.
+type LineError struct {
+	Line int // 1-based line number
+	Err  error
+}
+
+func (e *LineError) Error() string { return fmt.Sprintf("line %d: %v", e.Line, e.Err) }
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// A LinesDecoder decodes newline-delimited JSON, one value per line.
+// Blank lines are skipped. Lines may be arbitrarily long.
+type LinesDecoder struct {
+	r    *bufio.Reader
+	line int
+	buf  []byte
+	// DisallowUnknownFields causes an error when a line contains an object
+	// key that does not match a field of the destination struct.
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into interface values as json.Number.
+	UseNumber bool
+}
+
+// NewLinesDecoder returns a decoder that reads NDJSON from r.
+func NewLinesDecoder(r io.Reader) *LinesDecoder {
+	return &LinesDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode decodes the next non-blank line into v. It returns io.EOF when
+// the input is exhausted and a *LineError if the line is not valid JSON.
+func (d *LinesDecoder) Decode(v any) error {
+	for {
+		line, err := d.readLine()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == io.EOF {
+				return io.EOF
+			}
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		if d.DisallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		if d.UseNumber {
+			dec.UseNumber()
+		}
+		if derr := dec.Decode(v); derr != nil {
+			return &LineError{Line: d.line, Err: derr}
+		}
+		if _, terr := dec.Token(); terr != io.EOF {
+			return &LineError{Line: d.line, Err: errors.New("json: more than one value on line")}
+		}
+		return nil
+	}
+}
+
+// Line returns the number of the line most recently read.
+func (d *LinesDecoder) Line() int { return d.line }
+
+// readLine returns the next line without its line ending.
+func (d *LinesDecoder) readLine() ([]byte, error) {
+	line, err := d.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		d.buf = append(d.buf[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = d.r.ReadSlice('\n')
+			d.buf = append(d.buf, line...)
+		}
+		line = d.buf
+	}
+	if len(line) > 0 {
+		d.line++
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, err
+}
+
+// A LinesEncoder writes values as newline-delimited JSON. Output is
+// buffered; call Flush when done.
+type LinesEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewLinesEncoder returns an encoder that writes NDJSON to w.
+func NewLinesEncoder(w io.Writer) *LinesEncoder {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &LinesEncoder{w: bw, enc: enc}
+}
+
+// Encode writes v on its own line.
+func (e *LinesEncoder) Encode(v any) error { return e.enc.Encode(v) }
+
+// Flush writes any buffered data to the underlying writer.
+func (e *LinesEncoder) Flush() error { return e.w.Flush() }
+
+// An ArrayEncoder writes values as the elements of a single JSON array,
+// one at a time. Output is buffered; Close writes the closing bracket and
+// flushes.
+type ArrayEncoder struct {
+	w      *bufio.Writer
+	prefix string
+	indent string
+	n      int
+	closed bool
+}
+
+// NewArrayEncoder returns an encoder that writes an array to w.
+func NewArrayEncoder(w io.Writer) *ArrayEncoder {
+	return &ArrayEncoder{w: bufio.NewWriter(w)}
+}
+
+// SetIndent makes the encoder format the array as json.MarshalIndent would.
+// It must be called before the first Encode.
+func (e *ArrayEncoder) SetIndent(prefix, indent string) {
+	e.prefix, e.indent = prefix, indent
+}
+
+// Encode appends v to the array.
+func (e *ArrayEncoder) Encode(v any) error {
+	if e.closed {
+		return errors.New("json: Encode after Close")
+	}
+	b, err := marshal(v, e.prefix+e.indent, e.indent)
+	if err != nil {
+		return err
+	}
+	switch {
+	case e.n == 0:
+		e.w.WriteByte('[')
+	default:
+		e.w.WriteByte(',')
+	}
+	if e.indent != "" {
+		e.w.WriteString("\n" + e.prefix + e.indent)
+	}
+	e.w.Write(b)
+	e.n++
+	return nil
+}
+
+// Close terminates the array, writing "[]" if nothing was encoded, and
+// flushes the output.
+func (e *ArrayEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	switch {
+	case e.n == 0:
+		e.w.WriteString("[]")
+	case e.indent != "":
+		e.w.WriteString("\n" + e.prefix + "]")
+	default:
+		e.w.WriteByte(']')
+	}
+	e.w.WriteByte('\n')
+	return e.w.Flush()
+}
+
+// marshal encodes v without escaping HTML characters, indented if indent
+// is not empty, and without a trailing newline.
+func marshal(v any, prefix, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if indent != "" {
+		enc.SetIndent(prefix, indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Reformat copies the JSON values read from src to dst, one per line,
+// re-indented with prefix and indent as for json.Indent. An empty indent
+// compacts the output instead. Unlike json.Indent it works token by token,
+// so memory use does not grow with the size of the input. Numbers are
+// copied exactly as written.
+func Reformat(dst io.Writer, src io.Reader, prefix, indent string) error {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	w := bufio.NewWriter(dst)
+	f := formatter{w: w, prefix: prefix, indent: indent}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		if err := f.token(tok); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// A formatter writes a stream of tokens as formatted JSON.
+type formatter struct {
+	w      *bufio.Writer
+	prefix string
+	indent string
+	stack  []frame
+}
+
+// A frame is an open array or object.
+type frame struct {
+	object bool
+	n      int  // values written so far
+	key    bool // an object key has been written and its value is next
+}
+
+func (f *formatter) token(tok json.Token) error {
+	if d, ok := tok.(json.Delim); ok && (d == ']' || d == '}') {
+		top := f.stack[len(f.stack)-1]
+		f.stack = f.stack[:len(f.stack)-1]
+		if top.n > 0 {
+			f.newline()
+		}
+		f.w.WriteByte(byte(d))
+		f.endValue()
+		return nil
+	}
+
+	if n := len(f.stack); n > 0 {
+		top := &f.stack[n-1]
+		if top.object && !top.key {
+			// tok is an object key.
+			if top.n > 0 {
+				f.w.WriteByte(',')
+			}
+			f.newline()
+			if err := f.scalar(tok); err != nil {
+				return err
+			}
+			f.w.WriteByte(':')
+			if f.indent != "" {
+				f.w.WriteByte(' ')
+			}
+			top.key = true
+			return nil
+		}
+		if !top.object {
+			if top.n > 0 {
+				f.w.WriteByte(',')
+			}
+			f.newline()
+		}
+	}
+
+	if d, ok := tok.(json.Delim); ok {
+		f.w.WriteByte(byte(d))
+		f.stack = append(f.stack, frame{object: d == '{'})
+		return nil
+	}
+	if err := f.scalar(tok); err != nil {
+		return err
+	}
+	f.endValue()
+	return nil
+}
+
+// endValue records that a complete value has been written.
+func (f *formatter) endValue() {
+	if len(f.stack) == 0 {
+		f.w.WriteByte('\n')
+		return
+	}
+	top := &f.stack[len(f.stack)-1]
+	top.n++
+	top.key = false
+}
+
+func (f *formatter) newline() {
+	if f.indent == "" {
+		return
+	}
+	f.w.WriteByte('\n')
+	f.w.WriteString(f.prefix)
+	f.w.WriteString(strings.Repeat(f.indent, len(f.stack)))
+}
+
+func (f *formatter) scalar(tok json.Token) error {
+	switch t := tok.(type) {
+	case json.Number:
+		f.w.WriteString(t.String())
+		return nil
+	case string:
+		b, err := marshal(t, "", "")
+		if err != nil {
+			return err
+		}
+		f.w.Write(b)
+		return nil
+	}
+	b, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	f.w.Write(b)
+	return nil
+}
@@ -0,0 +1,248 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	ID    int               `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+}
+
+// genReader generates n JSON records lazily, as a top-level array or as
+// NDJSON, so that tests can stream inputs far larger than they hold.
+type genReader struct {
+	n, i   int
+	lines  bool
+	indent bool
+	buf    bytes.Buffer
+	done   bool
+}
+
+func (g *genReader) Read(p []byte) (int, error) {
+	for g.buf.Len() < len(p) && !g.done {
+		switch {
+		case g.i == 0 && !g.lines:
+			g.buf.WriteString("[")
+		}
+		if g.i < g.n {
+			if g.i > 0 && !g.lines {
+				g.buf.WriteString(",")
+			}
+			if g.indent {
+				g.buf.WriteString("\n  ")
+			}
+			fmt.Fprintf(&g.buf, `{"id":%d,"name":"record \"%d\"","tags":["a","bé",%q],"attrs":{"k":"%s"}}`,
+				g.i, g.i, fmt.Sprint(g.i%7), strings.Repeat("x", g.i%50))
+			if g.lines {
+				g.buf.WriteString("\n")
+			}
+			g.i++
+			continue
+		}
+		if !g.lines {
+			g.buf.WriteString("]\n")
+		}
+		g.done = true
+	}
+	if g.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return g.buf.Read(p)
+}
+
+// largeN is the number of records in the generated inputs: about 50MB, or
+// a few MB with -short.
+func largeN() int {
+	if testing.Short() {
+		return 40_000
+	}
+	return 500_000
+}
+
+// heapProbe tracks the peak live heap, sampled every so often.
+type heapProbe struct {
+	n    int
+	peak uint64
+}
+
+func (h *heapProbe) sample() {
+	if h.n++; h.n%20_000 != 0 {
+		return
+	}
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	h.peak = max(h.peak, ms.HeapAlloc)
+}
+
+// maxHeap is far below the size of the full inputs, which must never be
+// held in memory.
+const maxHeap = 32 << 20
+
+func checkRecord(t *testing.T, i int, r record) {
+	t.Helper()
+	if r.ID != i || r.Name != fmt.Sprintf("record \"%d\"", i) || len(r.Tags) != 3 || r.Tags[1] != "bé" || len(r.Attrs["k"]) != i%50 {
+		t.Fatalf("record %d decoded as %+v", i, r)
+	}
+}
+
+func TestArrayDecoderLarge(t *testing.T) {
+	n := largeN()
+	g := &genReader{n: n}
+	var probe heapProbe
+	i := 0
+	for r, err := range Elements[record](g) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRecord(t, i, r)
+		i++
+		probe.sample()
+	}
+	if i != n {
+		t.Fatalf("decoded %d records, want %d", i, n)
+	}
+	if probe.peak > maxHeap {
+		t.Fatalf("heap peaked at %d bytes", probe.peak)
+	}
+}
+
+func TestLinesDecoderLarge(t *testing.T) {
+	n := largeN()
+	d := NewLinesDecoder(&genReader{n: n, lines: true})
+	var probe heapProbe
+	for i := 0; ; i++ {
+		var r record
+		err := d.Decode(&r)
+		if err == io.EOF {
+			if i != n {
+				t.Fatalf("decoded %d records, want %d", i, n)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRecord(t, i, r)
+		probe.sample()
+	}
+	if probe.peak > maxHeap {
+		t.Fatalf("heap peaked at %d bytes", probe.peak)
+	}
+}
+
+func TestLinesDecoderLongLine(t *testing.T) {
+	long := strings.Repeat("y", 8<<20)
+	in := "{\"name\":\"short\"}\r\n\n{\"name\":\"" + long + "\"}\n{\"name\":\"after\"}"
+	d := NewLinesDecoder(strings.NewReader(in))
+	for _, want := range []string{"short", long, "after"} {
+		var r record
+		if err := d.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Name != want {
+			t.Fatalf("line %d: name of %d bytes, want %d", d.Line(), len(r.Name), len(want))
+		}
+	}
+	if d.Line() != 4 {
+		t.Fatalf("Line = %d, want 4", d.Line())
+	}
+	if err := d.Decode(new(record)); err != io.EOF {
+		t.Fatalf("Decode at end = %v, want io.EOF", err)
+	}
+}
+
+// TestReformatLarge pretty-prints a large generated array through a pipe
+// into an ArrayDecoder, so neither side ever holds the whole document.
+func TestReformatLarge(t *testing.T) {
+	n := largeN() / 4
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(Reformat(pw, &genReader{n: n, indent: true}, "", "\t"))
+	}()
+	var probe heapProbe
+	i := 0
+	for r, err := range Elements[record](pr) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRecord(t, i, r)
+		i++
+		probe.sample()
+	}
+	if i != n {
+		t.Fatalf("decoded %d records, want %d", i, n)
+	}
+	if probe.peak > maxHeap {
+		t.Fatalf("heap peaked at %d bytes", probe.peak)
+	}
+}
+
+func TestReformatExact(t *testing.T) {
+	in := `{"a":[1,2.50,{"b":null}],"c":"é"} [] {}`
+	var out strings.Builder
+	if err := Reformat(&out, strings.NewReader(in), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"a\": [\n    1,\n    2.50,\n    {\n      \"b\": null\n    }\n  ],\n  \"c\": \"é\"\n}\n[]\n{}\n"
+	if out.String() != want {
+		t.Fatalf("Reformat =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestArrayDecoderErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"a":1}`, ErrNotArray.Error()},
+		{``, io.ErrUnexpectedEOF.Error()},
+		{`[1,2`, "unexpected end of JSON input"},
+		{`[1`, "unexpected end of JSON input"},
+		{`[1] x`, "invalid character 'x'"},
+		{`[1] 2`, "unexpected data after top-level array"},
+	}
+	for _, tt := range tests {
+		var err error
+		for _, err = range Elements[int](strings.NewReader(tt.in)) {
+			if err != nil {
+				break
+			}
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestArrayEncoderRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	e := NewArrayEncoder(&b)
+	e.SetIndent("", " ")
+	for i := range 3 {
+		if err := e.Encode(record{ID: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	for r, err := range Elements[record](&b) {
+		if err != nil || r.ID != i {
+			t.Fatalf("element %d = %+v, %v", i, r, err)
+		}
+		i++
+	}
+	if i != 3 {
+		t.Fatalf("read back %d elements", i)
+	}
+}
@@ -0,0 +1,378 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// A Path is a compiled query in a subset of JSONPath. It selects values
+// from a document while reading it as a token stream, as described in the
+// "Decoding tokens" section of decoding/README.md: only the selected
+// values are decoded; everything else is skipped token by token.
+//
+// The supported syntax is:
+//
+//	$            the root value; every path starts with it
+//	.name        the member name of an object
+//	['name']     the same, for names that are not identifiers
+//	[3]          the element at index 3 of an array
+//	[1:5]        elements 1 to 4; either bound may be omitted
+//	[::2]        every other element; the step must be positive
+//	.* or [*]    every member of an object or element of an array
+//
+// Indexes cannot be negative, since the length of an array is not known
+// until it has been read to the end.
+type Path struct {
+	raw   string
+	steps []pathStep
+}
+
+type stepKind int
+
+const (
+	stepName stepKind = iota
+	stepIndex
+	stepSlice
+	stepWildcard
+)
+
+type pathStep struct {
+	kind       stepKind
+	name       string
+	start, end int // for stepIndex, start; for stepSlice, end < 0 means no bound
+	step       int
+}
+
+// matchKey reports whether the step selects the object member key.
+func (s *pathStep) matchKey(key string) bool {
+	return s.kind == stepWildcard || (s.kind == stepName && s.name == key)
+}
+
+// matchIndex reports whether the step selects array element i.
+func (s *pathStep) matchIndex(i int) bool {
+	switch s.kind {
+	case stepWildcard:
+		return true
+	case stepIndex:
+		return i == s.start
+	case stepSlice:
+		return i >= s.start && (s.end < 0 || i < s.end) && (i-s.start)%s.step == 0
+	}
+	return false
+}
+
+// done reports whether the step cannot select any array element at or
+// after index i.
+func (s *pathStep) done(i int) bool {
+	switch s.kind {
+	case stepIndex:
+		return i > s.start
+	case stepSlice:
+		return s.end >= 0 && i >= s.end
+	}
+	return false
+}
+
+// A PathError reports a malformed path expression.
+type PathError struct {
+	Path   string
+	Offset int // byte offset of the problem in Path
+	Msg    string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("json: invalid path %q at offset %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+// ParsePath compiles a path expression.
+func ParsePath(expr string) (*Path, error) {
+	p := &Path{raw: expr}
+	fail := func(off int, msg string) (*Path, error) {
+		return nil, &PathError{Path: expr, Offset: off, Msg: msg}
+	}
+	if !strings.HasPrefix(expr, "$") {
+		return fail(0, "must start with $")
+	}
+	i := 1
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			i++
+			if i < len(expr) && expr[i] == '*' {
+				p.steps = append(p.steps, pathStep{kind: stepWildcard})
+				i++
+				continue
+			}
+			start := i
+			for i < len(expr) && isIdentByte(expr[i]) {
+				i++
+			}
+			if i == start {
+				return fail(start, "expected member name")
+			}
+			p.steps = append(p.steps, pathStep{kind: stepName, name: expr[start:i]})
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return fail(i, "missing ]")
+			}
+			if q := expr[i+1]; q == '\'' || q == '"' {
+				// A quoted name may contain ']', so find its closing quote first.
+				name, n, err := unquoteName(expr[i+1:])
+				if err != nil {
+					return fail(i+1, err.Error())
+				}
+				if i+1+n >= len(expr) || expr[i+1+n] != ']' {
+					return fail(i+1+n, "expected ] after quoted name")
+				}
+				p.steps = append(p.steps, pathStep{kind: stepName, name: name})
+				i += n + 2
+				continue
+			}
+			s, err := parseBracket(expr[i+1 : i+end])
+			if err != nil {
+				return fail(i+1, err.Error())
+			}
+			p.steps = append(p.steps, s)
+			i += end + 1
+		default:
+			return fail(i, fmt.Sprintf("unexpected %q", expr[i]))
+		}
+	}
+	return p, nil
+}
+
+// MustParsePath is like ParsePath but panics if the expression is invalid.
+func MustParsePath(expr string) *Path {
+	p, err := ParsePath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the source text of the path.
+func (p *Path) String() string { return p.raw }
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '-' || c == '$' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// unquoteName reads a quoted member name at the start of s and returns it
+// along with the number of bytes it occupied, quotes included.
+func unquoteName(s string) (string, int, error) {
+	q := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated name")
+			}
+			i++
+			b.WriteByte(s[i])
+		case q:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated name")
+}
+
+// parseBracket parses the contents of an unquoted [...] step.
+func parseBracket(s string) (pathStep, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return pathStep{kind: stepWildcard}, nil
+	}
+	if !strings.Contains(s, ":") {
+		n, err := parseIndex(s)
+		if err != nil {
+			return pathStep{}, err
+		}
+		return pathStep{kind: stepIndex, start: n}, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return pathStep{}, fmt.Errorf("too many colons in slice")
+	}
+	st := pathStep{kind: stepSlice, end: -1, step: 1}
+	var err error
+	if parts[0] != "" {
+		if st.start, err = parseIndex(parts[0]); err != nil {
+			return pathStep{}, err
+		}
+	}
+	if parts[1] != "" {
+		if st.end, err = parseIndex(parts[1]); err != nil {
+			return pathStep{}, err
+		}
+	}
+	if len(parts) == 3 && parts[2] != "" {
+		if st.step, err = parseIndex(parts[2]); err != nil {
+			return pathStep{}, err
+		}
+		if st.step == 0 {
+			return pathStep{}, fmt.Errorf("slice step must be positive")
+		}
+	}
+	return st, nil
+}
+
+func parseIndex(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid index %q", s)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative index %d is not supported on a stream", n)
+	}
+	return n, nil
+}
+
+// Stream returns an iterator over the values selected by p in the next
+// JSON value read from dec, in document order. Iteration stops after the
+// first error, which is yielded with a nil value. If iteration is stopped
+// early, dec is left part-way through the value.
+func (p *Path) Stream(dec *json.Decoder) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		w := walker{dec: dec, steps: p.steps, yield: yield}
+		if err := w.walk(0); err != nil && err != errStop {
+			yield(nil, err)
+		}
+	}
+}
+
+// Find returns every value selected by p in the JSON document read from r.
+func (p *Path) Find(r io.Reader) ([]json.RawMessage, error) {
+	var found []json.RawMessage
+	for v, err := range p.Stream(json.NewDecoder(r)) {
+		if err != nil {
+			return found, err
+		}
+		found = append(found, v)
+	}
+	return found, nil
+}
+
+// First returns the first value selected by p in the JSON document read
+// from r, reading no further than needed. The boolean is false if nothing
+// matched.
+func (p *Path) First(r io.Reader) (json.RawMessage, bool, error) {
+	for v, err := range p.Stream(json.NewDecoder(r)) {
+		return v, err == nil, err
+	}
+	return nil, false, nil
+}
+
+// Extract evaluates path against the JSON document in data.
+func Extract(data []byte, path string) ([]json.RawMessage, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Find(bytes.NewReader(data))
+}
+
+// errStop aborts a walk when the consumer stops iterating.
+var errStop = errors.New("json: iteration stopped")
+
+type walker struct {
+	dec   *json.Decoder
+	steps []pathStep
+	yield func(json.RawMessage, error) bool
+}
+
+// walk matches steps[i:] against the next value in the stream, consuming it.
+func (w *walker) walk(i int) error {
+	if i == len(w.steps) {
+		var raw json.RawMessage
+		if err := w.dec.Decode(&raw); err != nil {
+			return err
+		}
+		if !w.yield(raw, nil) {
+			return errStop
+		}
+		return nil
+	}
+
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	step := &w.steps[i]
+	switch tok {
+	case json.Delim('{'):
+		for w.dec.More() {
+			key, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			if step.matchKey(key.(string)) {
+				err = w.walk(i + 1)
+			} else {
+				err = w.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token() // '}'
+		return err
+	case json.Delim('['):
+		for n := 0; w.dec.More(); n++ {
+			if step.matchIndex(n) {
+				err = w.walk(i + 1)
+			} else {
+				err = w.skip()
+			}
+			if err != nil {
+				return err
+			}
+			if step.done(n + 1) {
+				// Nothing further in this array can match; skip the rest.
+				for w.dec.More() {
+					if err := w.skip(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		_, err = w.dec.Token() // ']'
+		return err
+	}
+	// A scalar cannot contain the rest of the path.
+	return nil
+}
+
+// skip consumes the next value without decoding it.
+func (w *walker) skip() error {
+	depth := 0
+	for {
+		tok, err := w.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
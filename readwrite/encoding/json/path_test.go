@@ -0,0 +1,211 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParsePathErrors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		offset int
+		msg    string
+	}{
+		{"", 0, "must start with $"},
+		{"a.b", 0, "must start with $"},
+		{"$a", 1, "unexpected 'a'"},
+		{"$.", 2, "expected member name"},
+		{"$.a..b", 4, "expected member name"},
+		{"$.a b", 3, "unexpected ' '"},
+		{"$[1", 1, "missing ]"},
+		{"$['a", 1, "missing ]"},
+		{"$['a'x]", 5, "expected ] after quoted name"},
+		{`$['a\]`, 2, "unterminated name"},
+		{"$[]", 2, "invalid index"},
+		{"$[x]", 2, "invalid index"},
+		{"$[-1]", 2, "negative index"},
+		{"$[1:-2]", 2, "negative index"},
+		{"$[1:2:3:4]", 2, "too many colons"},
+		{"$[::0]", 2, "slice step must be positive"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePath(tt.expr)
+		var pe *PathError
+		if !errors.As(err, &pe) {
+			t.Errorf("ParsePath(%q) = %v, want a *PathError", tt.expr, err)
+			continue
+		}
+		if pe.Path != tt.expr || pe.Offset != tt.offset || !strings.Contains(pe.Msg, tt.msg) {
+			t.Errorf("ParsePath(%q) = %q at %d, want %q at %d", tt.expr, pe.Msg, pe.Offset, tt.msg, tt.offset)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParsePath did not panic")
+		}
+	}()
+	MustParsePath("$[")
+}
+
+const storeDoc = `{"store":{"book":[{"title":"A","price":8},{"title":"B","price":12,"tags":["x","y"]},{"title":"C","price":9}],"bike":{"color":"red"}},"a b":{"]":1,"it's":2},"n":null}`
+
+func TestPathFind(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"$", []string{storeDoc}},
+		{"$.store.bike.color", []string{`"red"`}},
+		{"$.store.book[1].title", []string{`"B"`}},
+		{"$.store.book[ 1 ].price", []string{`12`}},
+		{"$.store.book[*].title", []string{`"A"`, `"B"`, `"C"`}},
+		{"$.store.book.*.price", []string{`8`, `12`, `9`}},
+		{"$.store.book[0:2].price", []string{`8`, `12`}},
+		{"$.store.book[1:].title", []string{`"B"`, `"C"`}},
+		{"$.store.book[:1].title", []string{`"A"`}},
+		{"$.store.book[::2].title", []string{`"A"`, `"C"`}},
+		{"$.store.book[*].tags[1]", []string{`"y"`}},
+		{"$.store.*.color", []string{`"red"`}},
+		{"$[*]", []string{`{"book":[{"title":"A","price":8},{"title":"B","price":12,"tags":["x","y"]},{"title":"C","price":9}],"bike":{"color":"red"}}`, `{"]":1,"it's":2}`, `null`}},
+		{"$['a b'][']']", []string{`1`}},
+		{`$["a b"]['it\'s']`, []string{`2`}},
+		{"$.n", []string{`null`}},
+		{"$.n.x", nil},
+		{"$.missing", nil},
+		{"$.store.book[5]", nil},
+		{"$.store.bike[0]", nil},
+		{"$.store.book.title", nil},
+	}
+	for _, tt := range tests {
+		found, err := Extract([]byte(storeDoc), tt.path)
+		if err != nil {
+			t.Errorf("Extract(%q): %v", tt.path, err)
+			continue
+		}
+		if got := rawStrings(found); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Extract(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func rawStrings(found []json.RawMessage) []string {
+	var s []string
+	for _, v := range found {
+		s = append(s, string(v))
+	}
+	return s
+}
+
+// TestPathLarge selects values from a generated document far larger than
+// the heap is allowed to grow.
+func TestPathLarge(t *testing.T) {
+	n := largeN()
+	p := MustParsePath("$[*].tags[2]")
+	var probe heapProbe
+	i := 0
+	for v, err := range p.Stream(json.NewDecoder(&genReader{n: n, indent: true})) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("%q", fmt.Sprint(i%7)); string(v) != want {
+			t.Fatalf("match %d = %s, want %s", i, v, want)
+		}
+		i++
+		probe.sample()
+	}
+	if i != n {
+		t.Fatalf("%d matches, want %d", i, n)
+	}
+	if probe.peak > maxHeap {
+		t.Fatalf("heap peaked at %d bytes", probe.peak)
+	}
+}
+
+func TestPathNestedAfterLargeValue(t *testing.T) {
+	n := largeN() / 10
+	doc := func() io.Reader {
+		return io.MultiReader(
+			strings.NewReader(`{"pad":`),
+			&genReader{n: n},
+			strings.NewReader(`,"deep":{"a":[0,{"b":{"c":"found"}}]},"after":[1]}`),
+		)
+	}
+
+	v, ok, err := MustParsePath("$.deep.a[1].b.c").First(doc())
+	if err != nil || !ok || string(v) != `"found"` {
+		t.Fatalf("First = %s, %v, %v", v, ok, err)
+	}
+	v, ok, err = MustParsePath("$.pad[12345].id").First(doc())
+	if err != nil || !ok || string(v) != "12345" {
+		t.Fatalf("First = %s, %v, %v", v, ok, err)
+	}
+	if _, ok, err := MustParsePath("$.nothing").First(doc()); ok || err != nil {
+		t.Fatalf("First of a missing path = %v, %v", ok, err)
+	}
+}
+
+func TestPathStopEarly(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`[1,2,3,4]`))
+	var got []string
+	for v, err := range MustParsePath("$[*]").Stream(dec) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(v))
+		if len(got) == 2 {
+			break
+		}
+	}
+	if strings.Join(got, ",") != "1,2" {
+		t.Fatalf("got %v, want [1 2]", got)
+	}
+	// The decoder is left just after the last value yielded.
+	if tok, err := dec.Token(); err != nil || tok != 3.0 {
+		t.Fatalf("next token = %v, %v, want 3", tok, err)
+	}
+
+	// First reads no further than the first match, so an error after it
+	// is never seen.
+	r := io.MultiReader(strings.NewReader(`[{"a":1},`), iotest.ErrReader(errors.New("unreachable")))
+	v, ok, err := MustParsePath("$[*].a").First(r)
+	if err != nil || !ok || string(v) != "1" {
+		t.Fatalf("First = %s, %v, %v", v, ok, err)
+	}
+}
+
+func TestPathTruncated(t *testing.T) {
+	tests := []struct {
+		doc, path string
+		want      []string // values found before the error
+	}{
+		{`{"a":[1,2`, "$.a[*]", []string{"1", "2"}},
+		{`{"a":1`, "$.a", []string{"1"}},
+		{`[1,2`, "$[0]", []string{"1"}},
+		{`{"a":{"b":`, "$.a.b", nil},
+		{`{"skip":[1,`, "$.x", nil},
+		{`{"a"`, "$.a", nil},
+		{`{"a":tru`, "$.a", nil},
+		{`{`, "$.x", nil},
+		{`[`, "$[0]", nil},
+	}
+	for _, tt := range tests {
+		found, err := Extract([]byte(tt.doc), tt.path)
+		if err == nil || err == io.EOF {
+			t.Errorf("Extract(%q, %q) error = %v, want a truncation error", tt.doc, tt.path, err)
+		}
+		if got := rawStrings(found); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Extract(%q, %q) = %q, want %q", tt.doc, tt.path, got, tt.want)
+		}
+	}
+
+	// An empty input holds no document at all.
+	if _, err := Extract(nil, "$.a"); err != io.EOF {
+		t.Errorf("Extract of empty input = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package readwrite provides io.Reader and io.Writer wrappers that compose
+// streams, in the spirit of the io package described in the READMEs in this
+// directory: cancelling, counting, rate limiting, fanning out and strictly
+// limiting them.
+package readwrite
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// ErrLimitExceeded is returned by a reader from LimitReader when the
+// underlying stream holds more than the limit.
+var ErrLimitExceeded = errors.New("readwrite: read limit exceeded")
+
+// ContextReader returns a reader that fails with ctx.Err() once ctx is
+// done. The context is checked before each Read, so a Read that is already
+// blocked in r is not interrupted; to stop those, close r as well, for
+// example from context.AfterFunc.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// A CountingReader counts the bytes read through it. Count may be called
+// concurrently with Read, for example to report progress.
+type CountingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+// NewCountingReader returns a CountingReader reading from r.
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{r: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Count returns the number of bytes read so far.
+func (c *CountingReader) Count() int64 { return c.n.Load() }
+
+// A CountingWriter counts the bytes written through it. Count may be
+// called concurrently with Write.
+type CountingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+// NewCountingWriter returns a CountingWriter writing to w.
+func NewCountingWriter(w io.Writer) *CountingWriter {
+	return &CountingWriter{w: w}
+}
+
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Count returns the number of bytes written so far.
+func (c *CountingWriter) Count() int64 { return c.n.Load() }
+
+// LimitReader returns a reader that reads from r up to n bytes, like
+// io.LimitReader, but returns ErrLimitExceeded instead of io.EOF if r has
+// more than n bytes. Use it where truncated input would be silently
+// wrong, such as a request body that must be parsed whole. A negative n
+// is treated as zero.
+func LimitReader(r io.Reader, n int64) io.Reader {
+	return &limitReader{r: r, n: max(n, 0)}
+}
+
+type limitReader struct {
+	r   io.Reader
+	n   int64 // bytes left before the limit
+	err error
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	// Ask for one byte more than is left, to find out whether r ends at
+	// the limit.
+	if l.n < math.MaxInt64 && int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		n = int(l.n)
+		l.n = 0
+		l.err = ErrLimitExceeded
+		return n, l.err
+	}
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,87 @@
+package readwrite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		n       int64
+		want    string
+		wantErr error
+	}{
+		{"under", "abc", 5, "abc", nil},
+		{"exact", "abcde", 5, "abcde", nil},
+		{"over", "abcdef", 5, "abcde", ErrLimitExceeded},
+		{"zero", "", 0, "", nil},
+		{"zero over", "a", 0, "", ErrLimitExceeded},
+		{"negative", "", -3, "", nil},
+		{"negative over", "a", -3, "", ErrLimitExceeded},
+		{"max", "abc", math.MaxInt64, "abc", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := LimitReader(strings.NewReader(tt.input), tt.n)
+			got, err := io.ReadAll(r)
+			if string(got) != tt.want || err != tt.wantErr {
+				t.Fatalf("ReadAll = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+			// A one-byte reader exercises the check at the limit.
+			r = LimitReader(iotest.OneByteReader(strings.NewReader(tt.input)), tt.n)
+			got, err = io.ReadAll(r)
+			if string(got) != tt.want || err != tt.wantErr {
+				t.Fatalf("ReadAll byte by byte = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := ContextReader(ctx, strings.NewReader("abcdef"))
+	p := make([]byte, 3)
+	if n, err := r.Read(p); n != 3 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	cancel()
+	for range 2 {
+		if n, err := r.Read(p); n != 0 || err != context.Canceled {
+			t.Fatalf("Read after cancel = %d, %v, want 0, %v", n, err, context.Canceled)
+		}
+	}
+
+	// The context is checked before reading, so nothing is consumed.
+	src := strings.NewReader("abc")
+	if _, err := ContextReader(ctx, src).Read(p); err != context.Canceled || src.Len() != 3 {
+		t.Fatalf("Read = %v with %d bytes left", err, src.Len())
+	}
+}
+
+func TestCounting(t *testing.T) {
+	in := strings.Repeat("x", 10_000)
+	cr := NewCountingReader(iotest.HalfReader(strings.NewReader(in)))
+	var out bytes.Buffer
+	cw := NewCountingWriter(&out)
+	if n, err := io.Copy(cw, cr); n != int64(len(in)) || err != nil {
+		t.Fatalf("Copy = %d, %v", n, err)
+	}
+	if cr.Count() != int64(len(in)) || cw.Count() != int64(len(in)) {
+		t.Fatalf("counts %d and %d, want %d", cr.Count(), cw.Count(), len(in))
+	}
+
+	// Only bytes actually written are counted.
+	cw = NewCountingWriter(shortWriter{})
+	cw.Write([]byte("abcd"))
+	cw.Write([]byte("ef"))
+	if cw.Count() != 3 {
+		t.Fatalf("count %d after short writes, want 3", cw.Count())
+	}
+}
@@ -0,0 +1,222 @@
+// Package ioutil writes files so that a crash never leaves them half
+// written.
+//
+// Writing a file in place with os.Create and Write truncates it first, so
+// a crash, a full disk or a concurrent reader can see an empty or partial
+// file. The helpers here write a temporary file in the same directory,
+// flush it to stable storage, and rename it over the target, which
+// replaces the file in one step; the directory is then synced so the
+// rename itself survives a crash.
+package ioutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrModified is returned by WriteFileIfUnchanged when the file has
+// changed since it was read.
+var ErrModified = errors.New("ioutil: file modified since it was read")
+
+// A File is a temporary file that atomically replaces its target when
+// committed. Until then the target is untouched.
+type File struct {
+	*os.File
+	name string      // target path
+	mode fs.FileMode // mode the target gets
+	prev fs.FileInfo // the existing target, if any
+	done bool
+}
+
+// Create starts an atomic write of the named file. The new contents are
+// written to the returned File and take the file's place on Commit. If the
+// file exists, its permissions and, where the process may set it, its
+// owner are kept; otherwise it is created with perm, which unlike
+// os.Create is not masked by the umask.
+//
+// If name is a symbolic link, the file it points to is replaced and the
+// link kept, as os.Create would write through it. A dangling link is
+// replaced by a regular file.
+func Create(name string, perm fs.FileMode) (*File, error) {
+	// Resolve links first: the rename in Commit would replace the link
+	// itself, and the temporary file must be in the target's directory.
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+	prev, err := os.Stat(name)
+	switch {
+	case err == nil:
+		if !prev.Mode().IsRegular() {
+			return nil, &fs.PathError{Op: "create", Path: name, Err: errors.New("not a regular file")}
+		}
+		perm = prev.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	case errors.Is(err, fs.ErrNotExist):
+		prev = nil
+	default:
+		return nil, err
+	}
+
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f, name: name, mode: perm, prev: prev}, nil
+}
+
+// Commit replaces the target with the contents written so far, syncing
+// them and the directory to stable storage. The File is closed and its
+// temporary file gone whether or not Commit succeeds.
+func (f *File) Commit() error {
+	return f.commit(nil)
+}
+
+// commit is Commit with a check run just before the rename.
+func (f *File) commit(check func() error) (err error) {
+	if f.done {
+		return fmt.Errorf("ioutil: %s: already committed or aborted", f.name)
+	}
+	f.done = true
+	tmp := f.File.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	// Chown before Chmod: on Linux, changing the owner clears the setuid
+	// and setgid bits.
+	if f.prev != nil {
+		chown(f.File, f.prev)
+	}
+	if err := f.File.Chmod(f.mode); err != nil {
+		f.File.Close()
+		return err
+	}
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, f.name); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(f.name))
+}
+
+// Abort discards the contents written and leaves the target untouched. It
+// does nothing after Commit, so it can be deferred.
+func (f *File) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
+
+// WriteFile atomically replaces the named file with data, as described for
+// Create.
+func WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := Create(name, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// A Version identifies the state of a file when it was read, for
+// WriteFileIfUnchanged. The zero Version stands for a file that does not
+// exist.
+type Version struct {
+	info fs.FileInfo
+}
+
+// Exists reports whether the file existed when it was read.
+func (v Version) Exists() bool { return v.info != nil }
+
+// ModTime returns the modification time of the file when it was read.
+func (v Version) ModTime() time.Time {
+	if v.info == nil {
+		return time.Time{}
+	}
+	return v.info.ModTime()
+}
+
+// ReadFile reads the named file like os.ReadFile and returns its Version.
+// A file that does not exist yields no data, the zero Version and no
+// error, so that it can be created with WriteFileIfUnchanged.
+func ReadFile(name string) ([]byte, Version, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Version{}, nil
+	}
+	if err != nil {
+		return nil, Version{}, err
+	}
+	defer f.Close()
+	// Stat the open file, so the version matches what is read.
+	info, err := f.Stat()
+	if err != nil {
+		return nil, Version{}, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, Version{}, err
+	}
+	return data, Version{info: info}, nil
+}
+
+// WriteFileIfUnchanged is WriteFile for a read-modify-write cycle. It
+// returns ErrModified, leaving the file alone, if the file has been
+// replaced, modified, created or removed since ReadFile returned v.
+//
+// The check is made just before the file is replaced, so it catches
+// writers that finished earlier; it cannot stop one that races with the
+// final rename. Use a lock file for that.
+func WriteFileIfUnchanged(name string, data []byte, perm fs.FileMode, v Version) error {
+	f, err := Create(name, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.commit(func() error {
+		cur, err := os.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if v.info != nil {
+				return ErrModified
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if v.info == nil || !os.SameFile(cur, v.info) ||
+			!cur.ModTime().Equal(v.info.ModTime()) || cur.Size() != v.info.Size() {
+			return ErrModified
+		}
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+package ioutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "f")
+	if err := WriteFile(name, []byte("one"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(name, []byte("two"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, name, "two")
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o640 {
+		t.Fatalf("mode %v, want the existing 0640", perm)
+	}
+	checkNoTemp(t, filepath.Dir(name))
+}
+
+func TestCreateSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data", "target")
+	if err := os.Mkdir(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A relative link from another directory, through a second link.
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(filepath.Join("data", "target"), link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	link2 := filepath.Join(dir, "link2")
+	if err := os.Symlink("link", link2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(link2, []byte("new"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{link, link2} {
+		info, err := os.Lstat(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			t.Fatalf("%s was replaced by a regular file", l)
+		}
+	}
+	checkFile(t, target, "new")
+	checkNoTemp(t, dir)
+	checkNoTemp(t, filepath.Dir(target))
+}
+
+func TestCreateDanglingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("missing", link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := WriteFile(link, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Fatalf("dangling link not replaced: mode %v", info.Mode())
+	}
+	checkFile(t, link, "new")
+}
+
+func TestWriteFileIfUnchanged(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "f")
+	data, v, err := ReadFile(name)
+	if err != nil || data != nil || v.Exists() {
+		t.Fatalf("ReadFile of a missing file = %q, %v, %v", data, v, err)
+	}
+	if err := WriteFileIfUnchanged(name, []byte("one"), 0o644, v); err != nil {
+		t.Fatal(err)
+	}
+	// v still says the file does not exist.
+	if err := WriteFileIfUnchanged(name, []byte("two"), 0o644, v); !errors.Is(err, ErrModified) {
+		t.Fatalf("WriteFileIfUnchanged with a stale version = %v, want %v", err, ErrModified)
+	}
+	checkFile(t, name, "one")
+
+	_, v, err = ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileIfUnchanged(name, []byte("two"), 0o644, v); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, name, "two")
+	checkNoTemp(t, filepath.Dir(name))
+}
+
+func checkFile(t *testing.T, name, want string) {
+	t.Helper()
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("%s holds %q, want %q", name, got, want)
+	}
+}
+
+// checkNoTemp fails if a temporary file was left in dir.
+func checkNoTemp(t *testing.T, dir string) {
+	t.Helper()
+	tmp, err := filepath.Glob(filepath.Join(dir, ".*.tmp*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmp) > 0 {
+		t.Fatalf("temporary files left: %v", tmp)
+	}
+}
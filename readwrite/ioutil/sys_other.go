@@ -0,0 +1,15 @@
+//go:build !unix
+
+package ioutil
+
+import (
+	"io/fs"
+	"os"
+)
+
+// chown does nothing where files have no Unix owner.
+func chown(f *os.File, prev fs.FileInfo) {}
+
+// syncDir does nothing: directories cannot be synced on this system, and
+// renames are durable once they return.
+func syncDir(dir string) error { return nil }
@@ -0,0 +1,27 @@
+//go:build unix
+
+package ioutil
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// chown gives f the owner and group of prev, if the process may. Failure
+// is ignored: an unprivileged process can only keep its own ownership.
+func chown(f *os.File, prev fs.FileInfo) {
+	if st, ok := prev.Sys().(*syscall.Stat_t); ok {
+		f.Chown(int(st.Uid), int(st.Gid))
+	}
+}
+
+// syncDir flushes the directory entry changes in dir to stable storage.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
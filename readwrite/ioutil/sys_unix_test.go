@@ -0,0 +1,41 @@
+//go:build unix
+
+package ioutil
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileKeepsSetuid(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(name, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	const mode = 0o755 | fs.ModeSetuid | fs.ModeSetgid
+	if err := os.Chmod(name, mode); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != mode {
+		t.Skipf("cannot set setuid and setgid here: mode %v", info.Mode())
+	}
+
+	// Giving the new file its owner clears the bits on Linux, so they
+	// must be set after it.
+	if err := WriteFile(name, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, name, "new")
+	if info, err = os.Stat(name); err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != mode {
+		t.Fatalf("mode %v after WriteFile, want %v", info.Mode(), fs.FileMode(mode))
+	}
+}
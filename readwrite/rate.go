@@ -0,0 +1,129 @@
+package readwrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A Limiter is a token bucket that limits a flow of bytes to a rate with
+// bursts. It is safe for concurrent use, so one Limiter can cap the total
+// throughput of several readers and writers.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  int
+	tokens float64
+	last   time.Time
+
+	// now and after are time.Now and time.After, replaced in tests.
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+// NewLimiter returns a Limiter that allows bytesPerSec bytes per second on
+// average and up to burst bytes at once. The bucket starts full.
+func NewLimiter(bytesPerSec float64, burst int) *Limiter {
+	if bytesPerSec <= 0 || burst <= 0 {
+		panic(fmt.Sprintf("readwrite: invalid limiter rate %v or burst %d", bytesPerSec, burst))
+	}
+	return &Limiter{rate: bytesPerSec, burst: burst, tokens: float64(burst), last: time.Now(), now: time.Now, after: time.After}
+}
+
+// Burst returns the largest number of bytes WaitN accepts.
+func (l *Limiter) Burst() int { return l.burst }
+
+// WaitN blocks until n bytes may pass or ctx is done. It returns an error
+// if n exceeds the burst, or if ctx is done or would be before the wait
+// is over; in that case no tokens are used.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if n > l.burst {
+		return fmt.Errorf("readwrite: WaitN(%d) exceeds burst %d", n, l.burst)
+	}
+	l.mu.Lock()
+	now := l.now()
+	l.tokens = min(float64(l.burst), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	wait := time.Duration(0)
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+		l.tokens += float64(n)
+		l.mu.Unlock()
+		return context.DeadlineExceeded
+	}
+	l.mu.Unlock()
+	if wait == 0 {
+		return nil
+	}
+
+	select {
+	case <-l.after(wait):
+		return nil
+	case <-ctx.Done():
+		// Give the reservation back for others to use.
+		l.mu.Lock()
+		l.tokens += float64(n)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// RateReader returns a reader from r whose throughput l limits. Each Read
+// reads at most l.Burst() bytes and then waits for them to be allowed.
+func RateReader(ctx context.Context, r io.Reader, l *Limiter) io.Reader {
+	return &rateReader{ctx: ctx, r: r, l: l}
+}
+
+type rateReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *Limiter
+}
+
+func (r *rateReader) Read(p []byte) (int, error) {
+	if len(p) > r.l.burst {
+		p = p[:r.l.burst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.l.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// RateWriter returns a writer to w whose throughput l limits. Writes are
+// split into chunks of at most l.Burst() bytes, each of which waits to be
+// allowed before it is written.
+func RateWriter(ctx context.Context, w io.Writer, l *Limiter) io.Writer {
+	return &rateWriter{ctx: ctx, w: w, l: l}
+}
+
+type rateWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *Limiter
+}
+
+func (w *rateWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), w.l.burst)]
+		if err := w.l.WaitN(w.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,193 @@
+package readwrite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fakeClock stands in for the Limiter's clock. Waits return at once and
+// move the clock forward, unless block is set, in which case they never
+// return.
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	waited time.Duration
+	block  bool
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if !c.block {
+		c.t = c.t.Add(d)
+		c.waited += d
+		ch <- c.t
+	}
+	return ch
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// takeWaited returns the time waited since the last call.
+func (c *fakeClock) takeWaited() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.waited
+	c.waited = 0
+	return d
+}
+
+func newFakeLimiter(bytesPerSec float64, burst int) (*Limiter, *fakeClock) {
+	l := NewLimiter(bytesPerSec, burst)
+	c := &fakeClock{t: l.last}
+	l.now, l.after = c.now, c.after
+	return l, c
+}
+
+func TestLimiterBurstAndRate(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newFakeLimiter(100, 10)
+
+	// The bucket starts full.
+	for range 2 {
+		if err := l.WaitN(ctx, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := clock.takeWaited(); d != 0 {
+		t.Fatalf("burst waited %v", d)
+	}
+	// Empty, 10 bytes take 100ms at 100 bytes a second.
+	if err := l.WaitN(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if d := clock.takeWaited(); d != 100*time.Millisecond {
+		t.Fatalf("waited %v, want 100ms", d)
+	}
+	// Tokens accrue with time, up to the burst.
+	clock.advance(50 * time.Millisecond)
+	if err := l.WaitN(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(time.Hour)
+	if err := l.WaitN(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if d := clock.takeWaited(); d != 0 {
+		t.Fatalf("waited %v with tokens available", d)
+	}
+	if err := l.WaitN(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if d := clock.takeWaited(); d != 10*time.Millisecond {
+		t.Fatalf("waited %v after an hour's refill was used, want 10ms", d)
+	}
+
+	if err := l.WaitN(ctx, 11); err == nil {
+		t.Fatal("WaitN above the burst succeeded")
+	}
+}
+
+func TestLimiterContext(t *testing.T) {
+	l, clock := newFakeLimiter(100, 10)
+	l.WaitN(context.Background(), 10)
+
+	// A deadline before the wait would end fails at once and uses nothing.
+	ctx, cancel := context.WithDeadline(context.Background(), clock.now().Add(50*time.Millisecond))
+	defer cancel()
+	if err := l.WaitN(ctx, 10); err != context.DeadlineExceeded {
+		t.Fatalf("WaitN = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if l.tokens != 0 {
+		t.Fatalf("tokens = %v after a refused wait, want 0", l.tokens)
+	}
+
+	// Cancelling during a wait gives the tokens back.
+	clock.block = true
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.WaitN(ctx, 10) }()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("WaitN = %v, want %v", err, context.Canceled)
+	}
+	if l.tokens != 0 {
+		t.Fatalf("tokens = %v after a cancelled wait, want 0", l.tokens)
+	}
+}
+
+// sizeRecorder records the size of each Write.
+type sizeRecorder struct {
+	bytes.Buffer
+	sizes []int
+}
+
+func (w *sizeRecorder) Write(p []byte) (int, error) {
+	w.sizes = append(w.sizes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestRateReader(t *testing.T) {
+	l, clock := newFakeLimiter(100, 10)
+	in := strings.Repeat("0123456789", 100)
+	r := RateReader(context.Background(), strings.NewReader(in), l)
+
+	p := make([]byte, 64)
+	if n, err := r.Read(p); n != 10 || err != nil {
+		t.Fatalf("Read = %d, %v, want the burst of 10", n, err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || in[10:] != string(rest) {
+		t.Fatalf("ReadAll = %d bytes, %v", len(rest), err)
+	}
+	// After the initial burst, 990 bytes at 100 a second.
+	if d := clock.takeWaited(); d != 9900*time.Millisecond {
+		t.Fatalf("waited %v, want 9.9s", d)
+	}
+}
+
+func TestRateWriter(t *testing.T) {
+	l, clock := newFakeLimiter(100, 10)
+	var out sizeRecorder
+	w := RateWriter(context.Background(), &out, l)
+	in := []byte(strings.Repeat("0123456789", 100))
+	if n, err := w.Write(in); n != len(in) || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if !bytes.Equal(out.Bytes(), in) {
+		t.Fatal("written bytes differ")
+	}
+	for _, n := range out.sizes {
+		if n > 10 {
+			t.Fatalf("wrote a chunk of %d bytes, over the burst", n)
+		}
+	}
+	if d := clock.takeWaited(); d != 9900*time.Millisecond {
+		t.Fatalf("waited %v, want 9.9s", d)
+	}
+
+	// A cancelled wait stops the write part-way.
+	clock.block = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = RateWriter(ctx, &out, l)
+	if n, err := w.Write(in); n != 0 || err != context.Canceled {
+		t.Fatalf("Write = %d, %v, want 0, %v", n, err, context.Canceled)
+	}
+}
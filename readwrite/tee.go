@@ -0,0 +1,85 @@
+package readwrite
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// An ErrorPolicy says what a Tee does when one of its writers fails.
+type ErrorPolicy int
+
+const (
+	// Required writers fail the whole Write, which returns their error.
+	// Writers after the failing one do not receive the data.
+	Required ErrorPolicy = iota
+	// BestEffort writers are dropped after their first error, which is
+	// kept for Errors; the Tee carries on with the others.
+	BestEffort
+)
+
+// ErrAllOutputsFailed is wrapped by the error a Tee returns once every one
+// of its outputs has failed.
+var ErrAllOutputsFailed = errors.New("readwrite: every tee output has failed")
+
+// A TeeOutput is a writer in a Tee and its error policy.
+type TeeOutput struct {
+	W      io.Writer
+	Policy ErrorPolicy
+}
+
+// A Tee writes everything written to it to several writers, like
+// io.MultiWriter, but lets each writer choose whether its failure stops
+// the rest. A short write counts as a failure with io.ErrShortWrite.
+type Tee struct {
+	outs []TeeOutput
+	errs []error // first error of each output
+	err  error   // first error of a Required output
+}
+
+// NewTee returns a Tee writing to outs in order.
+func NewTee(outs ...TeeOutput) *Tee {
+	return &Tee{outs: outs, errs: make([]error, len(outs))}
+}
+
+// Write writes p to every output still in use. It returns len(p) unless a
+// Required output fails or no output is left, after which every Write
+// returns an error: the Required output's, or one wrapping
+// ErrAllOutputsFailed and the errors of the outputs.
+func (t *Tee) Write(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	written := false
+	for i, o := range t.outs {
+		if t.errs[i] != nil {
+			continue
+		}
+		n, err := o.W.Write(p)
+		if err == nil && n != len(p) {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			written = true
+			continue
+		}
+		t.errs[i] = err
+		if o.Policy == Required {
+			t.err = err
+			return 0, err
+		}
+	}
+	if !written && len(t.outs) > 0 {
+		t.err = fmt.Errorf("%w: %w", ErrAllOutputsFailed, t.Errors())
+		return 0, t.err
+	}
+	return len(p), nil
+}
+
+// Err returns the first error of the output at index i, in the order
+// given to NewTee.
+func (t *Tee) Err(i int) error { return t.errs[i] }
+
+// Errors returns the errors of all outputs that have failed joined
+// together, or nil.
+func (t *Tee) Errors() error { return errors.Join(t.errs...) }
@@ -0,0 +1,102 @@
+package readwrite
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// failWriter fails every Write from the after-th on, counting from zero.
+type failWriter struct {
+	after  int
+	writes int
+	err    error
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > w.after {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// shortWriter writes half of p without an error.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) { return len(p) / 2, nil }
+
+func TestTeeRequired(t *testing.T) {
+	errDisk := errors.New("disk full")
+	var a, b bytes.Buffer
+	bad := &failWriter{after: 1, err: errDisk}
+	tee := NewTee(TeeOutput{W: &a}, TeeOutput{W: bad}, TeeOutput{W: &b})
+
+	if n, err := tee.Write([]byte("one")); n != 3 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if n, err := tee.Write([]byte("two")); n != 0 || err != errDisk {
+		t.Fatalf("Write = %d, %v, want 0, %v", n, err, errDisk)
+	}
+	// Outputs after the failing one did not get the data, and the error
+	// is sticky.
+	if a.String() != "onetwo" || b.String() != "one" {
+		t.Fatalf("outputs hold %q and %q", a.String(), b.String())
+	}
+	if _, err := tee.Write([]byte("three")); err != errDisk {
+		t.Fatalf("Write after failure = %v, want %v", err, errDisk)
+	}
+	if bad.writes != 2 || tee.Err(1) != errDisk || tee.Err(0) != nil {
+		t.Fatalf("writes %d, errors %v, %v", bad.writes, tee.Err(0), tee.Err(1))
+	}
+}
+
+func TestTeeBestEffort(t *testing.T) {
+	errNet := errors.New("network down")
+	var a bytes.Buffer
+	bad := &failWriter{err: errNet}
+	tee := NewTee(TeeOutput{W: bad, Policy: BestEffort}, TeeOutput{W: shortWriter{}, Policy: BestEffort}, TeeOutput{W: &a})
+
+	for _, s := range []string{"one", "two"} {
+		if n, err := tee.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	if a.String() != "onetwo" {
+		t.Fatalf("required output holds %q", a.String())
+	}
+	// Failed outputs are dropped after their first error.
+	if bad.writes != 1 {
+		t.Fatalf("failed output written %d times", bad.writes)
+	}
+	if tee.Err(0) != errNet || tee.Err(1) != io.ErrShortWrite || tee.Err(2) != nil {
+		t.Fatalf("errors %v, %v, %v", tee.Err(0), tee.Err(1), tee.Err(2))
+	}
+	if err := tee.Errors(); !errors.Is(err, errNet) || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("Errors = %v", err)
+	}
+}
+
+func TestTeeAllBestEffortFailed(t *testing.T) {
+	errNet := errors.New("network down")
+	flaky := &failWriter{after: 1, err: errNet}
+	tee := NewTee(TeeOutput{W: &failWriter{err: errNet}, Policy: BestEffort}, TeeOutput{W: flaky, Policy: BestEffort})
+
+	// One output still works.
+	if n, err := tee.Write([]byte("one")); n != 3 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	// Now none does.
+	for range 2 {
+		n, err := tee.Write([]byte("two"))
+		if n != 0 || !errors.Is(err, ErrAllOutputsFailed) || !errors.Is(err, errNet) {
+			t.Fatalf("Write = %d, %v, want 0 and %v", n, err, ErrAllOutputsFailed)
+		}
+	}
+
+	// A Tee without outputs discards data, like io.MultiWriter.
+	if n, err := NewTee().Write([]byte("x")); n != 1 || err != nil {
+		t.Fatalf("Write to an empty Tee = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,261 @@
+package traverse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"time"
+)
+
+// FollowOptions configures Follow. A nil *FollowOptions uses the defaults.
+type FollowOptions struct {
+	// Poll is how often to check the file for new data, truncation and
+	// rotation. Zero means 250ms.
+	Poll time.Duration
+	// FromStart emits the lines already in the file. By default only
+	// lines appended after Follow starts are emitted.
+	FromStart bool
+}
+
+// Follow returns an iterator over the lines appended to the named file,
+// like tail -f. Line endings are removed; a partial line is held back
+// until its ending is written.
+//
+// The file is polled. If it is truncated, following restarts from its
+// beginning; a file truncated and refilled past the point already read
+// between two polls, as by logrotate's copytruncate, is spotted because
+// the bytes just before that point have changed. If it is rotated, that
+// is, the name now refers to a different file, the rest of the old file is
+// read and following continues from the start of the new one. If the file does not
+// exist yet, Follow waits for it to appear.
+//
+// Iteration ends without an error when ctx is done. Other errors are
+// yielded with a nil line and end the iteration. Each line is only valid
+// until the next one is yielded.
+func Follow(ctx context.Context, name string, opts *FollowOptions) iter.Seq2[[]byte, error] {
+	var o FollowOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Poll <= 0 {
+		o.Poll = 250 * time.Millisecond
+	}
+	return func(yield func([]byte, error) bool) {
+		f := &follower{name: name, buf: make([]byte, 32<<10)}
+		defer f.close()
+		err := f.run(ctx, o, yield)
+		if err != nil && !errors.Is(err, errStopped) && ctx.Err() == nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// errStopped ends a follow when the consumer stops iterating.
+var errStopped = errors.New("traverse: iteration stopped")
+
+type follower struct {
+	name    string
+	file    *os.File
+	info    fs.FileInfo // of file, when it was opened
+	off     int64       // bytes of file consumed
+	partial []byte      // start of a line without its ending yet
+	last    []byte      // up to lastLen bytes of file just before off
+	check   []byte      // scratch space for truncated
+	buf     []byte
+}
+
+// lastLen is how many bytes before the read offset are kept to check that
+// the file has not been truncated and refilled.
+const lastLen = 64
+
+func (f *follower) run(ctx context.Context, o FollowOptions, yield func([]byte, error) bool) error {
+	fromStart := o.FromStart
+	t := time.NewTicker(o.Poll)
+	defer t.Stop()
+	for {
+		if f.file == nil {
+			opened, err := f.open(fromStart)
+			if err != nil {
+				return err
+			}
+			// A file that appears later is new: read all of it.
+			fromStart = true
+			if !opened {
+				if err := wait(ctx, t); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err := f.drain(yield); err != nil {
+			return err
+		}
+		if err := wait(ctx, t); err != nil {
+			return err
+		}
+
+		cur, err := os.Stat(f.name)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Rotated away and not yet replaced; keep reading the old file
+			// in case its writer still has it open.
+			continue
+		case err != nil:
+			return err
+		case !os.SameFile(cur, f.info):
+			// Rotated: finish the old file, then switch to the new one.
+			if err := f.drain(yield); err != nil {
+				return err
+			}
+			if err := f.flushPartial(yield); err != nil {
+				return err
+			}
+			f.close()
+		default:
+			truncated, err := f.truncated(cur.Size())
+			if err != nil {
+				return err
+			}
+			if !truncated {
+				continue
+			}
+			// Start again from the beginning.
+			if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			f.off = 0
+			f.partial = f.partial[:0]
+			f.last = f.last[:0]
+		}
+	}
+}
+
+// truncated reports whether the file, now size bytes long, has been
+// truncated since it was last read: it is shorter than what was read, or
+// the bytes just before f.off are no longer the ones read there.
+func (f *follower) truncated(size int64) (bool, error) {
+	if size < f.off {
+		return true, nil
+	}
+	if len(f.last) == 0 {
+		return false, nil
+	}
+	p := append(f.check[:0], f.last...)
+	f.check = p
+	_, err := f.file.ReadAt(p, f.off-int64(len(p)))
+	if err == io.EOF {
+		// Shrunk since the Stat.
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(p, f.last), nil
+}
+
+// remember keeps the end of data, just read, in f.last.
+func (f *follower) remember(data []byte) {
+	f.last = append(f.last, data[max(0, len(data)-lastLen):]...)
+	if n := len(f.last); n > lastLen {
+		f.last = f.last[:copy(f.last, f.last[n-lastLen:])]
+	}
+}
+
+// open opens the file, reporting false if it does not exist.
+func (f *follower) open(fromStart bool) (bool, error) {
+	file, err := os.Open(f.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return false, err
+	}
+	f.file, f.info, f.off = file, info, 0
+	f.last = f.last[:0]
+	if !fromStart {
+		if f.off, err = file.Seek(0, io.SeekEnd); err != nil {
+			file.Close()
+			f.file = nil
+			return false, err
+		}
+		// Remember the end of what is skipped, to spot a truncation.
+		p := make([]byte, min(f.off, lastLen))
+		if _, err := file.ReadAt(p, f.off-int64(len(p))); err == nil {
+			f.remember(p)
+		}
+	}
+	return true, nil
+}
+
+func (f *follower) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// drain reads the file to its current end and yields every complete line.
+func (f *follower) drain(yield func([]byte, error) bool) error {
+	for {
+		n, err := f.file.Read(f.buf)
+		f.off += int64(n)
+		data := f.buf[:n]
+		f.remember(data)
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				f.partial = append(f.partial, data...)
+				break
+			}
+			line := data[:i]
+			if len(f.partial) > 0 {
+				f.partial = append(f.partial, line...)
+				line = f.partial
+			}
+			if !yield(bytes.TrimSuffix(line, []byte("\r")), nil) {
+				return errStopped
+			}
+			f.partial = f.partial[:0]
+			data = data[i+1:]
+		}
+		if err == io.EOF || n == 0 && err == nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// flushPartial yields a last line that was never terminated.
+func (f *follower) flushPartial(yield func([]byte, error) bool) error {
+	if len(f.partial) == 0 {
+		return nil
+	}
+	line := f.partial
+	f.partial = f.partial[:0]
+	if !yield(bytes.TrimSuffix(line, []byte("\r")), nil) {
+		return errStopped
+	}
+	return nil
+}
+
+// wait waits for the next tick or for ctx to be done.
+func wait(ctx context.Context, t *time.Ticker) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package traverse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// follow runs Follow in the background and returns its lines on a
+// channel, which is closed when the iteration ends.
+func follow(t *testing.T, ctx context.Context, name string, opts *FollowOptions) <-chan string {
+	t.Helper()
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for line, err := range Follow(ctx, name, opts) {
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			select {
+			case ch <- string(line):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func expect(t *testing.T, ch <-chan string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got, ok := <-ch:
+			if !ok {
+				t.Fatalf("iteration ended, want %q", w)
+			}
+			if got != w {
+				t.Fatalf("line %q, want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no line, want %q", w)
+		}
+	}
+}
+
+func appendFile(t *testing.T, name, s string) {
+	t.Helper()
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var fastPoll = &FollowOptions{Poll: 5 * time.Millisecond, FromStart: true}
+
+func TestFollowAppend(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	appendFile(t, name, "a\r\nb\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := follow(t, ctx, name, fastPoll)
+
+	expect(t, ch, "a", "b")
+	appendFile(t, name, "par")
+	time.Sleep(20 * time.Millisecond) // a few polls with the line incomplete
+	appendFile(t, name, "tial\nc\n")
+	expect(t, ch, "partial", "c")
+
+	cancel()
+	if line, ok := <-ch; ok {
+		t.Fatalf("line %q after cancel", line)
+	}
+}
+
+func TestFollowFromEnd(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	appendFile(t, name, "old\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Without FromStart the existing line is skipped. Appending before
+	// Follow opens the file could skip the new line too, so keep
+	// appending until one arrives.
+	ch := follow(t, ctx, name, &FollowOptions{Poll: 5 * time.Millisecond})
+	for {
+		appendFile(t, name, "new\n")
+		select {
+		case got := <-ch:
+			if got != "new" {
+				t.Fatalf("line %q, want %q", got, "new")
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestFollowTruncate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	appendFile(t, name, "first\nsecond\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := follow(t, ctx, name, fastPoll)
+	expect(t, ch, "first", "second")
+
+	if err := os.WriteFile(name, []byte("z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, ch, "z")
+
+	appendFile(t, name, "y\n")
+	expect(t, ch, "y")
+
+	// Truncated and refilled past the offset already read within one
+	// poll, as with copytruncate and a busy writer: the size alone never
+	// shows the truncation.
+	if err := os.WriteFile(name, []byte("a longer line written after truncation\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, ch, "a longer line written after truncation")
+}
+
+func TestFollowRotate(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "log")
+	appendFile(t, name, "a\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := follow(t, ctx, name, fastPoll)
+	expect(t, ch, "a")
+
+	// Rotate the way a logger does: rename, finish writing the old file,
+	// then start a new one.
+	old := filepath.Join(dir, "log.1")
+	if err := os.Rename(name, old); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, old, "b\nunterminated")
+	time.Sleep(20 * time.Millisecond) // poll while the name is missing
+	appendFile(t, name, "c\n")
+	expect(t, ch, "b", "unterminated", "c")
+}
+
+func TestFollowCreated(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The file does not exist yet, so all of it is new even without
+	// FromStart.
+	ch := follow(t, ctx, name, &FollowOptions{Poll: 5 * time.Millisecond})
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, name, "hello\nworld\n")
+	expect(t, ch, "hello", "world")
+}
@@ -0,0 +1,140 @@
+// Package traverse moves around within files using io.Seeker, as the
+// README in this directory describes: reading lines backwards from the
+// end, and following a file as it grows, like tail -f.
+package traverse
+
+import (
+	"bytes"
+	"io"
+	"iter"
+)
+
+// reverseChunk is the size of the blocks a ReverseReader reads.
+const reverseChunk = 64 << 10
+
+// A ReverseReader reads the lines of a seekable stream from last to first.
+// It reads the stream in blocks from the end, so showing the last lines
+// of a large file costs only a read of its tail. Line endings, "\n" or
+// "\r\n", are removed; a line ending at the very end does not start an
+// empty last line.
+type ReverseReader struct {
+	r       io.ReadSeeker
+	pos     int64  // offset of data in the stream
+	data    []byte // bytes read but not yet returned, ending before the last line returned
+	started bool
+	done    bool
+	offset  int64 // offset of the line last returned
+}
+
+// NewReverseReader returns a ReverseReader for r, starting at its end.
+func NewReverseReader(r io.ReadSeeker) *ReverseReader {
+	return &ReverseReader{r: r}
+}
+
+// ReadLine returns the line before the one it last returned. The slice is
+// only valid until the next call. It returns io.EOF after the first line
+// of the stream.
+func (r *ReverseReader) ReadLine() ([]byte, error) {
+	if !r.started {
+		end, err := r.r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		r.pos = end
+		r.started = true
+		if err := r.fill(); err != nil {
+			return nil, err
+		}
+		r.data = bytes.TrimSuffix(r.data, []byte("\n"))
+		if len(r.data) == 0 && r.pos == 0 {
+			r.done = true // empty, or a single line ending
+			if end > 0 {
+				return r.emit(0, 0)
+			}
+		}
+	}
+	for {
+		if r.done {
+			return nil, io.EOF
+		}
+		if i := bytes.LastIndexByte(r.data, '\n'); i >= 0 {
+			return r.emit(i+1, i)
+		}
+		if r.pos == 0 {
+			r.done = true
+			return r.emit(0, 0)
+		}
+		if err := r.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// emit returns data[from:] as a line and keeps data[:to] for later.
+func (r *ReverseReader) emit(from, to int) ([]byte, error) {
+	line := bytes.TrimSuffix(r.data[from:], []byte("\r"))
+	r.offset = r.pos + int64(from)
+	r.data = r.data[:to]
+	return line, nil
+}
+
+// fill reads the block before data and prepends it. Blocks grow with
+// data, so a line of any length takes a logarithmic number of reads.
+func (r *ReverseReader) fill() error {
+	n := int64(max(reverseChunk, len(r.data)))
+	n = min(n, r.pos)
+	buf := make([]byte, int(n)+len(r.data))
+	if _, err := r.r.Seek(r.pos-n, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r.r, buf[:n]); err != nil {
+		return err
+	}
+	copy(buf[n:], r.data)
+	r.data = buf
+	r.pos -= n
+	return nil
+}
+
+// Offset returns the byte offset in the stream of the line last returned.
+func (r *ReverseReader) Offset() int64 { return r.offset }
+
+// Lines returns an iterator over the lines from last to first. Iteration
+// stops after the first error, which is yielded with a nil line.
+func (r *ReverseReader) Lines() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			line, err := r.ReadLine()
+			if err == io.EOF {
+				return
+			}
+			if !yield(line, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Tail returns the last n lines of r in their original order. It returns
+// no lines, without reading r, if n is not positive.
+func Tail(r io.ReadSeeker, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	// n may be far more than the lines in r, so let the slice grow.
+	var lines []string
+	rr := NewReverseReader(r)
+	for line, err := range rr.Lines() {
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, string(line))
+		if len(lines) == n {
+			break
+		}
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines, nil
+}
@@ -0,0 +1,128 @@
+package traverse
+
+import (
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func readReverse(t *testing.T, input string) (lines []string, offsets []int64) {
+	t.Helper()
+	r := NewReverseReader(strings.NewReader(input))
+	for line, err := range r.Lines() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(line))
+		offsets = append(offsets, r.Offset())
+	}
+	return lines, offsets
+}
+
+func TestReverseReader(t *testing.T) {
+	long := strings.Repeat("x", 3*reverseChunk+17)
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"newline", "\n", []string{""}},
+		{"one", "a", []string{"a"}},
+		{"one terminated", "a\n", []string{"a"}},
+		{"crlf", "a\r\nb\r\n", []string{"b", "a"}},
+		{"blank lines", "a\n\nb\n\n", []string{"", "b", "", "a"}},
+		{"long lines", "a\n" + long + "\nb", []string{"b", long, "a"}},
+		{"across blocks", strings.Repeat("y", reverseChunk-1) + "\nz\n", []string{"z", strings.Repeat("y", reverseChunk-1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, offsets := readReverse(t, tt.input)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("lines %q, want %q", got, tt.want)
+			}
+			for i, off := range offsets {
+				if !strings.HasPrefix(tt.input[off:], got[i]) {
+					t.Errorf("Offset of line %q = %d", got[i], off)
+				}
+			}
+		})
+	}
+}
+
+func TestReverseReaderEOF(t *testing.T) {
+	r := NewReverseReader(strings.NewReader("a\nb"))
+	for range 2 {
+		if _, err := r.ReadLine(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 2 {
+		if line, err := r.ReadLine(); err != io.EOF {
+			t.Fatalf("ReadLine after the first line = %q, %v, want EOF", line, err)
+		}
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadSeeker
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadSeeker.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestTail(t *testing.T) {
+	const input = "1\n2\n3\n4\n5\n"
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{-1, nil},
+		{0, nil},
+		{1, []string{"5"}},
+		{3, []string{"3", "4", "5"}},
+		{5, []string{"1", "2", "3", "4", "5"}},
+		{6, []string{"1", "2", "3", "4", "5"}},
+		{math.MaxInt, []string{"1", "2", "3", "4", "5"}},
+	}
+	for _, tt := range tests {
+		r := &countingReader{ReadSeeker: strings.NewReader(input)}
+		got, err := Tail(r, tt.n)
+		if err != nil {
+			t.Fatalf("Tail(%d): %v", tt.n, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Tail(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+		if tt.n <= 0 && r.n != 0 {
+			t.Errorf("Tail(%d) read %d bytes", tt.n, r.n)
+		}
+	}
+}
+
+func TestTailLargeFile(t *testing.T) {
+	var b strings.Builder
+	for i := range 100_000 {
+		b.WriteString(strings.Repeat("-", i%50))
+		b.WriteString("\n")
+	}
+	b.WriteString("last")
+	r := &countingReader{ReadSeeker: strings.NewReader(b.String())}
+	got, err := Tail(r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{strings.Repeat("-", 99_999%50), "last"}; !slices.Equal(got, want) {
+		t.Fatalf("Tail = %q, want %q", got, want)
+	}
+	if r.n > reverseChunk {
+		t.Fatalf("Tail read %d bytes of %d for two lines", r.n, b.Len())
+	}
+}
@@ -0,0 +1,210 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// TestAgainstBytesBuffer runs the same random operations on a Buffer and a
+// bytes.Buffer and checks that they agree.
+func TestAgainstBytesBuffer(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var b Buffer
+	var ref bytes.Buffer
+	text := "héllo, wörld ✓ \xff plain ascii\n"
+	for i := range 20000 {
+		var got, want any
+		switch op := r.IntN(10); op {
+		case 0:
+			s := text[:r.IntN(len(text))]
+			b.WriteString(s)
+			ref.WriteString(s)
+		case 1:
+			c := byte(r.IntN(256))
+			b.WriteByte(c)
+			ref.WriteByte(c)
+		case 2:
+			c := rune(r.IntN(0x2000))
+			b.WriteRune(c)
+			ref.WriteRune(c)
+		case 3:
+			p := make([]byte, r.IntN(20))
+			q := make([]byte, len(p))
+			n, err := b.Read(p)
+			m, rerr := ref.Read(q)
+			got, want = [3]any{n, err, string(p[:n])}, [3]any{m, rerr, string(q[:m])}
+		case 4:
+			c, err := b.ReadByte()
+			d, rerr := ref.ReadByte()
+			got, want = [2]any{c, err}, [2]any{d, rerr}
+		case 5:
+			c, n, err := b.ReadRune()
+			d, m, rerr := ref.ReadRune()
+			got, want = [3]any{c, n, err}, [3]any{d, m, rerr}
+		case 6:
+			got, want = b.UnreadByte() == nil, ref.UnreadByte() == nil
+		case 7:
+			got, want = b.UnreadRune() == nil, ref.UnreadRune() == nil
+		case 8:
+			if b.Len() == 0 {
+				// bytes.Buffer lets UnreadByte follow an empty
+				// ReadBytes; Buffer requires a byte to have been read.
+				continue
+			}
+			line, err := b.ReadBytes('\n')
+			rline, rerr := ref.ReadBytes('\n')
+			got, want = [2]any{string(line), err}, [2]any{string(rline), rerr}
+		case 9:
+			n := r.IntN(8)
+			got, want = string(b.Next(n)), string(ref.Next(n))
+		}
+		if got != want {
+			t.Fatalf("op %d: got %v, want %v", i, got, want)
+		}
+		if b.String() != ref.String() {
+			t.Fatalf("op %d: contents %q, want %q", i, b.String(), ref.String())
+		}
+	}
+}
+
+func TestReadFromWriteTo(t *testing.T) {
+	src := strings.Repeat("0123456789", 10000)
+	var b Buffer
+	n, err := b.ReadFrom(strings.NewReader(src))
+	if err != nil || n != int64(len(src)) {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	var out strings.Builder
+	m, err := b.WriteTo(&out)
+	if err != nil || m != n || out.String() != src || b.Len() != 0 {
+		t.Fatalf("WriteTo = %d, %v; %d bytes left", m, err, b.Len())
+	}
+}
+
+func TestGrowKeepsContents(t *testing.T) {
+	b := New([]byte("abcdef"))
+	b.Next(3)
+	b.Grow(1000)
+	if b.String() != "def" || b.Available() < 1000 {
+		t.Fatalf("after Grow: %q, %d available", b.String(), b.Available())
+	}
+	b.Truncate(1)
+	if b.String() != "d" {
+		t.Fatalf("after Truncate: %q", b.String())
+	}
+}
+
+func TestPool(t *testing.T) {
+	b := Get()
+	b.WriteString("data")
+	Put(b)
+	if c := Get(); c.Len() != 0 {
+		t.Fatalf("pooled buffer has %d bytes", c.Len())
+	}
+	big := NewSize(maxPooledSize + 1)
+	Put(big) // must not panic, and is dropped
+}
+
+var (
+	chunk = []byte(strings.Repeat("x", 100))
+	runes = strings.Repeat("aé✓", 10)
+)
+
+func BenchmarkWrite(b *testing.B) {
+	b.Run("Buffer", func(b *testing.B) {
+		b.SetBytes(100 * int64(len(chunk)))
+		for b.Loop() {
+			var buf Buffer
+			for range 100 {
+				buf.Write(chunk)
+			}
+		}
+	})
+	b.Run("Pooled", func(b *testing.B) {
+		b.SetBytes(100 * int64(len(chunk)))
+		for b.Loop() {
+			buf := Get()
+			for range 100 {
+				buf.Write(chunk)
+			}
+			Put(buf)
+		}
+	})
+	b.Run("bytes.Buffer", func(b *testing.B) {
+		b.SetBytes(100 * int64(len(chunk)))
+		for b.Loop() {
+			var buf bytes.Buffer
+			for range 100 {
+				buf.Write(chunk)
+			}
+		}
+	})
+}
+
+func BenchmarkReadWriteCycle(b *testing.B) {
+	p := make([]byte, 64)
+	b.Run("Buffer", func(b *testing.B) {
+		var buf Buffer
+		for b.Loop() {
+			buf.Write(chunk)
+			for buf.Len() > 0 {
+				buf.Read(p)
+			}
+		}
+	})
+	b.Run("bytes.Buffer", func(b *testing.B) {
+		var buf bytes.Buffer
+		for b.Loop() {
+			buf.Write(chunk)
+			for buf.Len() > 0 {
+				buf.Read(p)
+			}
+		}
+	})
+}
+
+func BenchmarkRunes(b *testing.B) {
+	b.Run("Buffer", func(b *testing.B) {
+		var buf Buffer
+		for b.Loop() {
+			buf.WriteString(runes)
+			for {
+				if _, _, err := buf.ReadRune(); err == io.EOF {
+					break
+				}
+			}
+		}
+	})
+	b.Run("bytes.Buffer", func(b *testing.B) {
+		var buf bytes.Buffer
+		for b.Loop() {
+			buf.WriteString(runes)
+			for {
+				if _, _, err := buf.ReadRune(); err == io.EOF {
+					break
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkReadFrom(b *testing.B) {
+	src := strings.Repeat("0123456789", 10000)
+	b.Run("Buffer", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		for b.Loop() {
+			var buf Buffer
+			buf.ReadFrom(strings.NewReader(src))
+		}
+	})
+	b.Run("bytes.Buffer", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		for b.Loop() {
+			var buf bytes.Buffer
+			buf.ReadFrom(strings.NewReader(src))
+		}
+	})
+}
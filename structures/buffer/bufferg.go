@@ -0,0 +1,162 @@
+// Package buffer implements a growable byte buffer with separate read and
+// write cursors.
+//
+// A Buffer is similar to bytes.Buffer: bytes are written at the write
+// cursor and read from the read cursor, and the space in front of the read
+// cursor is reclaimed before the buffer grows. It implements io.Reader,
+// io.Writer, io.ByteScanner, io.RuneScanner, io.WriterTo and io.ReaderFrom.
+// Buffers can be recycled through Get and Put to avoid allocating a new
+// backing array for every use.
+//
+// The buffer is split across files by concern: this file holds the
+// storage and growth, bufferr.go the read side and bufferw.go the write
+// side.
+package buffer
+
+import (
+	"errors"
+	"sync"
+)
+
+// smallBufferSize is the initial allocation for a buffer that has none.
+const smallBufferSize = 64
+
+// maxPooledSize is the largest capacity Put keeps, so that one huge
+// payload does not pin its memory in the pool.
+const maxPooledSize = 64 << 10
+
+// ErrTooLarge is passed to panic if memory cannot be allocated to store
+// data in a buffer.
+var ErrTooLarge = errors.New("buffer: too large")
+
+// A Buffer is a variable-sized buffer of bytes. The zero value is an empty
+// buffer ready to use. A Buffer is not safe for concurrent use.
+type Buffer struct {
+	buf []byte // contents are buf[r:w]; len(buf) is the allocated size
+	r   int    // read cursor
+	w   int    // write cursor
+	// last records the most recent read so that UnreadByte and
+	// UnreadRune can step back over it: opRead after a byte read, the rune
+	// size after ReadRune, and opInvalid when no unread is possible.
+	last int
+}
+
+const (
+	opRead    = -1 // any read that may be followed by UnreadByte
+	opInvalid = 0  // no unread is possible
+)
+
+// New returns a buffer whose initial contents are buf, which it takes
+// ownership of. Writes append after the existing contents.
+func New(buf []byte) *Buffer {
+	return &Buffer{buf: buf[:cap(buf)], w: len(buf)}
+}
+
+// NewSize returns an empty buffer with at least size bytes of capacity.
+func NewSize(size int) *Buffer {
+	return &Buffer{buf: make([]byte, size)}
+}
+
+// Len returns the number of unread bytes.
+func (b *Buffer) Len() int { return b.w - b.r }
+
+// Cap returns the capacity of the buffer's underlying storage.
+func (b *Buffer) Cap() int { return len(b.buf) }
+
+// Available returns how many bytes can be written without growing.
+func (b *Buffer) Available() int { return len(b.buf) - b.w }
+
+// Bytes returns the unread portion of the buffer. The slice is only valid
+// until the next modification of the buffer.
+func (b *Buffer) Bytes() []byte { return b.buf[b.r:b.w] }
+
+// String returns the unread portion of the buffer as a string. Like
+// bytes.Buffer, a nil *Buffer returns "<nil>".
+func (b *Buffer) String() string {
+	if b == nil {
+		return "<nil>"
+	}
+	return string(b.buf[b.r:b.w])
+}
+
+// Reset empties the buffer but keeps its storage for future writes.
+func (b *Buffer) Reset() {
+	b.r, b.w, b.last = 0, 0, opInvalid
+}
+
+// Truncate discards all but the first n unread bytes.
+// It panics if n is negative or greater than the length of the buffer.
+func (b *Buffer) Truncate(n int) {
+	if n < 0 || n > b.Len() {
+		panic("buffer: truncation out of range")
+	}
+	b.w = b.r + n
+	b.last = opInvalid
+	if n == 0 {
+		b.Reset()
+	}
+}
+
+// Grow grows the buffer's capacity, if necessary, so that at least n more
+// bytes can be written without another allocation.
+// It panics if n is negative and with ErrTooLarge if the buffer cannot grow.
+func (b *Buffer) Grow(n int) {
+	if n < 0 {
+		panic("buffer: negative count")
+	}
+	b.grow(n)
+}
+
+// grow makes room for n more bytes after the write cursor, first by
+// sliding the unread bytes to the front and only then by reallocating.
+func (b *Buffer) grow(n int) {
+	if n <= len(b.buf)-b.w {
+		return
+	}
+	m := b.Len()
+	if m == 0 && b.r != 0 {
+		b.Reset()
+		if n <= len(b.buf) {
+			return
+		}
+	}
+	if b.buf == nil && n <= smallBufferSize {
+		b.buf = make([]byte, smallBufferSize)
+		return
+	}
+	if n <= len(b.buf)/2-m {
+		// Reclaim the consumed space. Only slide when it frees at least
+		// half the buffer so that copying stays amortized O(1).
+		copy(b.buf, b.buf[b.r:b.w])
+	} else {
+		size := 2*len(b.buf) + n
+		if size < len(b.buf) || size < 0 {
+			panic(ErrTooLarge)
+		}
+		buf := make([]byte, size)
+		copy(buf, b.buf[b.r:b.w])
+		b.buf = buf
+	}
+	b.r, b.w = 0, m
+}
+
+// pool recycles buffers handed back with Put.
+var pool = sync.Pool{
+	New: func() any { return new(Buffer) },
+}
+
+// Get returns an empty buffer from the pool, allocating one if the pool is
+// empty. Return it with Put once it is no longer needed.
+func Get() *Buffer {
+	return pool.Get().(*Buffer)
+}
+
+// Put resets b and returns it to the pool. Buffers that have grown beyond
+// 64KB are dropped instead. b must not be used after Put.
+func Put(b *Buffer) {
+	if b.Cap() > maxPooledSize {
+		return
+	}
+	b.Reset()
+	pool.Put(b)
+}
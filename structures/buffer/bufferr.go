@@ -0,0 +1,145 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+var errUnread = errors.New("buffer: unread must follow a successful read")
+
+// Read reads the next len(p) bytes from the buffer or until the buffer is
+// drained. If the buffer has no data to return, err is io.EOF (unless
+// len(p) is zero).
+func (b *Buffer) Read(p []byte) (n int, err error) {
+	b.last = opInvalid
+	if b.Len() == 0 {
+		b.Reset()
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n = copy(p, b.buf[b.r:b.w])
+	b.r += n
+	if n > 0 {
+		b.last = opRead
+	}
+	return n, nil
+}
+
+// Next returns a slice containing the next n bytes from the buffer,
+// advancing the buffer as if the bytes had been returned by Read. If there
+// are fewer than n bytes, Next returns the entire buffer. The slice is only
+// valid until the next modification of the buffer.
+func (b *Buffer) Next(n int) []byte {
+	b.last = opInvalid
+	m := min(n, b.Len())
+	data := b.buf[b.r : b.r+m]
+	b.r += m
+	if m > 0 {
+		b.last = opRead
+	}
+	return data
+}
+
+// ReadByte reads and returns the next byte from the buffer.
+// If no byte is available, it returns io.EOF.
+func (b *Buffer) ReadByte() (byte, error) {
+	if b.Len() == 0 {
+		b.Reset()
+		return 0, io.EOF
+	}
+	c := b.buf[b.r]
+	b.r++
+	b.last = opRead
+	return c, nil
+}
+
+// UnreadByte unreads the last byte returned by the most recent successful
+// read operation that read at least one byte.
+func (b *Buffer) UnreadByte() error {
+	if b.last == opInvalid {
+		return errUnread
+	}
+	b.last = opInvalid
+	b.r--
+	return nil
+}
+
+// ReadRune reads and returns the next UTF-8-encoded Unicode code point from
+// the buffer. If no bytes are available, it returns io.EOF. If the bytes
+// are an erroneous UTF-8 encoding, it consumes one byte and returns
+// U+FFFD, 1.
+func (b *Buffer) ReadRune() (r rune, size int, err error) {
+	if b.Len() == 0 {
+		b.Reset()
+		return 0, 0, io.EOF
+	}
+	if c := b.buf[b.r]; c < utf8.RuneSelf {
+		b.r++
+		b.last = 1
+		return rune(c), 1, nil
+	}
+	r, size = utf8.DecodeRune(b.buf[b.r:b.w])
+	b.r += size
+	b.last = size
+	return r, size, nil
+}
+
+// UnreadRune unreads the last rune returned by ReadRune. Unlike
+// UnreadByte it only succeeds directly after ReadRune.
+func (b *Buffer) UnreadRune() error {
+	if b.last <= opInvalid {
+		return errUnread
+	}
+	b.r -= b.last
+	b.last = opInvalid
+	return nil
+}
+
+// ReadBytes reads until the first occurrence of delim in the input,
+// returning a slice containing the data up to and including the delimiter.
+// If ReadBytes encounters the end of the buffer before finding a
+// delimiter, it returns the data read before the end and io.EOF.
+func (b *Buffer) ReadBytes(delim byte) (line []byte, err error) {
+	data := b.buf[b.r:b.w]
+	n := bytes.IndexByte(data, delim) + 1
+	if n == 0 {
+		n = len(data)
+		err = io.EOF
+	}
+	line = append([]byte(nil), data[:n]...)
+	b.r += n
+	b.last = opInvalid
+	if n > 0 {
+		b.last = opRead
+	}
+	return line, err
+}
+
+// WriteTo writes data to w until the buffer is drained or an error occurs.
+// The return value n is the number of bytes written; any error encountered
+// during the write is also returned.
+func (b *Buffer) WriteTo(w io.Writer) (n int64, err error) {
+	b.last = opInvalid
+	if m := b.Len(); m > 0 {
+		written, err := w.Write(b.buf[b.r:b.w])
+		if written > m {
+			panic("buffer: invalid Write count")
+		}
+		b.r += written
+		n = int64(written)
+		if err != nil {
+			return n, err
+		}
+		// All bytes should have been written, by definition of
+		// Write method in io.Writer
+		if written != m {
+			return n, io.ErrShortWrite
+		}
+	}
+	b.Reset()
+	return n, nil
+}
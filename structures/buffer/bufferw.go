@@ -0,0 +1,80 @@
+package buffer
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// minRead is the minimum slice size passed to a Read call by ReadFrom.
+const minRead = 512
+
+// Write appends the contents of p to the buffer, growing the buffer as
+// needed. The return value n is the length of p; err is always nil. If the
+// buffer becomes too large, Write will panic with ErrTooLarge.
+func (b *Buffer) Write(p []byte) (n int, err error) {
+	b.last = opInvalid
+	b.grow(len(p))
+	n = copy(b.buf[b.w:], p)
+	b.w += n
+	return n, nil
+}
+
+// WriteString appends the contents of s to the buffer, growing the buffer
+// as needed. The return value n is the length of s; err is always nil.
+func (b *Buffer) WriteString(s string) (n int, err error) {
+	b.last = opInvalid
+	b.grow(len(s))
+	n = copy(b.buf[b.w:], s)
+	b.w += n
+	return n, nil
+}
+
+// WriteByte appends the byte c to the buffer, growing the buffer as needed.
+// The returned error is always nil, but is included to match
+// bufio.Writer's WriteByte.
+func (b *Buffer) WriteByte(c byte) error {
+	b.last = opInvalid
+	b.grow(1)
+	b.buf[b.w] = c
+	b.w++
+	return nil
+}
+
+// WriteRune appends the UTF-8 encoding of Unicode code point r to the
+// buffer, returning its length and an error, which is always nil but is
+// included to match bufio.Writer's WriteRune.
+func (b *Buffer) WriteRune(r rune) (n int, err error) {
+	if uint32(r) < utf8.RuneSelf {
+		b.WriteByte(byte(r))
+		return 1, nil
+	}
+	b.last = opInvalid
+	b.grow(utf8.UTFMax)
+	n = utf8.EncodeRune(b.buf[b.w:], r)
+	b.w += n
+	return n, nil
+}
+
+// ReadFrom reads data from r until EOF and appends it to the buffer,
+// growing the buffer as needed. The return value n is the number of bytes
+// read. Any error except io.EOF encountered during the read is also
+// returned. If the buffer becomes too large, ReadFrom will panic with
+// ErrTooLarge.
+func (b *Buffer) ReadFrom(r io.Reader) (n int64, err error) {
+	b.last = opInvalid
+	for {
+		b.grow(minRead)
+		m, e := r.Read(b.buf[b.w:])
+		if m < 0 {
+			panic("buffer: reader returned negative count from Read")
+		}
+		b.w += m
+		n += int64(m)
+		if e == io.EOF {
+			return n, nil // e is EOF, so return nil explicitly
+		}
+		if e != nil {
+			return n, e
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package buffer
+
+import (
+	"bufio"
+	"errors"
+)
+
+var (
+	// ErrFieldTooLong is returned when a field exceeds Splitter.MaxFieldLen.
+	ErrFieldTooLong = errors.New("buffer: field too long")
+	// ErrQuote is returned for an unterminated quoted field or for text
+	// between a closing quote and the next separator.
+	ErrQuote = errors.New("buffer: malformed quoted field")
+	// ErrEscape is returned when input ends with a lone escape character.
+	ErrEscape = errors.New("buffer: escape at end of input")
+)
+
+// A Splitter splits records into fields without allocating.
+//
+// Records end at a newline ("\n" or "\r\n"); fields end at any of the
+// separator bytes. Fields are returned as sub-slices of the input. When a
+// field contains quotes or escapes they are removed in place, so the input
+// bytes of that field are overwritten.
+//
+// A Splitter can be used directly on byte slices with Split, or on a stream
+// through its bufio.SplitFuncs, ScanRecords and FieldScanner, which plug
+// into a bufio.Scanner:
+//
+//	s := buffer.Splitter{Separators: ",", Quote: '"', Escape: '"'}
+//	scanner := bufio.NewScanner(os.Stdin)
+//	scanner.Split(s.ScanRecords)
+//	var fields [][]byte
+//	var err error
+//	for scanner.Scan() {
+//		fields, err = s.Split(scanner.Bytes(), fields[:0])
+//		...
+//	}
+type Splitter struct {
+	// Separators holds the bytes that end a field. Empty means ",".
+	Separators string
+	// Quote, if not zero, starts and ends a quoted field, which may contain
+	// separators and newlines.
+	Quote byte
+	// Escape, if not zero, makes the following byte literal. If it is the
+	// same as Quote, it only applies inside quoted fields, where a doubled
+	// quote stands for one quote as in CSV.
+	Escape byte
+	// TrimSpace removes spaces and tabs around each field, outside any quotes.
+	TrimSpace bool
+	// MaxFieldLen, if positive, is the longest field accepted, measured
+	// before unquoting. Longer fields fail with ErrFieldTooLong.
+	MaxFieldLen int
+}
+
+// Split splits the first record in data into fields, appending them to dst
+// and returning the extended slice. Reusing dst across calls avoids all
+// allocation. Fields are sub-slices of data, which may be modified in
+// place to remove quotes and escapes.
+func (s *Splitter) Split(data []byte, dst [][]byte) ([][]byte, error) {
+	for {
+		f, _, err := s.scan(data, true)
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, s.field(data, f))
+		if f.term == 0 || f.term == '\n' {
+			return dst, nil
+		}
+		data = data[f.advance:]
+	}
+}
+
+// ScanRecords is a bufio.SplitFunc that returns each record, without its
+// line ending, for a later call to Split. Unlike bufio.ScanLines, newlines
+// inside quoted fields do not end the record.
+func (s *Splitter) ScanRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	pos := 0
+	for {
+		f, more, err := s.scan(data[pos:], atEOF)
+		if err != nil {
+			return 0, nil, err
+		}
+		if more {
+			// Request more data.
+			return 0, nil, nil
+		}
+		pos += f.advance
+		switch f.term {
+		case 0:
+			return pos, data[:pos], nil
+		case '\n':
+			return pos, dropCR(data[:pos-1]), nil
+		}
+	}
+}
+
+// FieldScanner returns a bufio.SplitFunc that returns each field of the
+// input in turn, treating the ends of records like separators. The
+// function keeps state between calls, so use a new one for each Scanner.
+func (s *Splitter) FieldScanner() bufio.SplitFunc {
+	// trailing records that the last field ended with a separator at the
+	// very end of the data seen so far; if the input ends there, one final
+	// empty field remains.
+	trailing := false
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) == 0 {
+			if atEOF && trailing {
+				trailing = false
+				return 0, []byte{}, bufio.ErrFinalToken
+			}
+			return 0, nil, nil
+		}
+		f, more, err := s.scan(data, atEOF)
+		if err != nil {
+			return 0, nil, err
+		}
+		if more {
+			// Request more data.
+			return 0, nil, nil
+		}
+		trailing = f.term != 0 && f.term != '\n' && f.advance == len(data)
+		return f.advance, s.field(data, f), nil
+	}
+}
+
+// An extent locates a field found by scan.
+type extent struct {
+	start, end int  // the field's bytes, before unescaping
+	advance    int  // bytes consumed, including the terminator
+	term       byte // the separator or '\n' that ended the field, or 0 at end of input
+	quoted     bool
+	escaped    bool // the field contains quotes or escapes to remove
+}
+
+// scan finds the end of the field at the start of data without modifying
+// data. It reports more if the field may continue beyond data and atEOF is
+// false.
+func (s *Splitter) scan(data []byte, atEOF bool) (f extent, more bool, err error) {
+	i := 0
+	if s.TrimSpace {
+		i = s.skipSpace(data, i)
+	}
+
+	if s.Quote != 0 && i < len(data) && data[i] == s.Quote {
+		f.quoted = true
+		f.start = i + 1
+		j := f.start
+		for {
+			if j >= len(data) {
+				if atEOF {
+					return f, false, ErrQuote
+				}
+				return f, true, s.checkLen(j - f.start)
+			}
+			if err := s.checkLen(j - f.start); err != nil {
+				return f, false, err
+			}
+			c := data[j]
+			if s.Escape != 0 && s.Escape != s.Quote && c == s.Escape {
+				if j+1 >= len(data) {
+					if atEOF {
+						return f, false, ErrEscape
+					}
+					return f, true, nil
+				}
+				f.escaped = true
+				j += 2
+				continue
+			}
+			if c == s.Quote {
+				if s.Escape == s.Quote {
+					if j+1 >= len(data) && !atEOF {
+						// Cannot tell a closing quote from a doubled one yet.
+						return f, true, nil
+					}
+					if j+1 < len(data) && data[j+1] == s.Quote {
+						f.escaped = true
+						j += 2
+						continue
+					}
+				}
+				f.end = j
+				i = j + 1
+				break
+			}
+			j++
+		}
+		if s.TrimSpace {
+			i = s.skipSpace(data, i)
+		}
+	} else {
+		f.start = i
+		for i < len(data) {
+			c := data[i]
+			if c == '\n' || s.isSep(c) {
+				break
+			}
+			if s.Escape != 0 && s.Escape != s.Quote && c == s.Escape {
+				if i+1 >= len(data) {
+					if atEOF {
+						return f, false, ErrEscape
+					}
+					return f, true, nil
+				}
+				f.escaped = true
+				i++
+			}
+			i++
+			if err := s.checkLen(i - f.start); err != nil {
+				return f, false, err
+			}
+		}
+		f.end = i
+	}
+
+	// i is now at the terminator, if any.
+	switch {
+	case i == len(data):
+		if !atEOF {
+			return f, true, nil
+		}
+		f.advance = i
+	case data[i] == '\n' || s.isSep(data[i]):
+		f.term = data[i]
+		f.advance = i + 1
+	case data[i] == '\r' && f.quoted:
+		if i+1 == len(data) && !atEOF {
+			return f, true, nil
+		}
+		if i+1 == len(data) || data[i+1] != '\n' {
+			return f, false, ErrQuote
+		}
+		f.term = '\n'
+		f.advance = i + 2
+	default:
+		return f, false, ErrQuote
+	}
+
+	if !f.quoted {
+		if f.term == '\n' && f.end > f.start && data[f.end-1] == '\r' {
+			f.end--
+		}
+		if s.TrimSpace {
+			for f.end > f.start && isSpace(data[f.end-1]) {
+				f.end--
+			}
+		}
+	}
+	return f, false, nil
+}
+
+// field returns the field located by f, removing quotes and escapes in place.
+func (s *Splitter) field(data []byte, f extent) []byte {
+	b := data[f.start:f.end]
+	if !f.escaped {
+		return b
+	}
+	w := 0
+	for r := 0; r < len(b); r++ {
+		c := b[r]
+		if s.Escape != 0 && c == s.Escape && (s.Escape != s.Quote || f.quoted) && r+1 < len(b) {
+			r++
+			c = b[r]
+		}
+		b[w] = c
+		w++
+	}
+	return b[:w]
+}
+
+func (s *Splitter) isSep(c byte) bool {
+	if s.Separators == "" {
+		return c == ','
+	}
+	for i := 0; i < len(s.Separators); i++ {
+		if s.Separators[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSpace returns the index of the first byte at or after i that is not
+// a space, treating separators as non-space.
+func (s *Splitter) skipSpace(data []byte, i int) int {
+	for i < len(data) && isSpace(data[i]) && !s.isSep(data[i]) {
+		i++
+	}
+	return i
+}
+
+func (s *Splitter) checkLen(n int) error {
+	if s.MaxFieldLen > 0 && n > s.MaxFieldLen {
+		return ErrFieldTooLong
+	}
+	return nil
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' }
+
+// dropCR drops a terminal \r from the data.
+func dropCR(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == '\r' {
+		return data[0 : len(data)-1]
+	}
+	return data
+}
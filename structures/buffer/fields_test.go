@@ -0,0 +1,190 @@
+package buffer
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var (
+	csvSplitter  = Splitter{Quote: '"', Escape: '"'}
+	bareSplitter = Splitter{}
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Splitter
+		input string
+		want  []string
+		err   error
+	}{
+		{"plain", bareSplitter, "a,b,c", []string{"a", "b", "c"}, nil},
+		{"empty input", bareSplitter, "", []string{""}, nil},
+		{"empty last field", bareSplitter, "a,b,", []string{"a", "b", ""}, nil},
+		{"empty fields", bareSplitter, ",,", []string{"", "", ""}, nil},
+		{"ends at newline", bareSplitter, "a,b\nc,d\n", []string{"a", "b"}, nil},
+		{"crlf", bareSplitter, "a,b\r\nc", []string{"a", "b"}, nil},
+		{"quoted separator", csvSplitter, `"a,b",c`, []string{"a,b", "c"}, nil},
+		{"doubled quote", csvSplitter, `"say ""hi""",x`, []string{`say "hi"`, "x"}, nil},
+		{"quoted newline", csvSplitter, "\"a\r\nb\",c\n", []string{"a\r\nb", "c"}, nil},
+		{"quoted empty last field", csvSplitter, `a,""`, []string{"a", ""}, nil},
+		{"quoted field then crlf", csvSplitter, "\"a\"\r\nb", []string{"a"}, nil},
+		{"quote inside bare field", csvSplitter, `a"b,c`, []string{`a"b`, "c"}, nil},
+		{"several separators", Splitter{Separators: ",;\t"}, "a;b\tc,d", []string{"a", "b", "c", "d"}, nil},
+		{"comma not a separator", Splitter{Separators: "|"}, "a,b|c", []string{"a,b", "c"}, nil},
+		{"escape", Splitter{Quote: '"', Escape: '\\'}, `a\,b,"c\"d",e`, []string{"a,b", `c"d`, "e"}, nil},
+		{"trim space", Splitter{Quote: '"', TrimSpace: true}, " a ,\t\" b \" , c\t", []string{"a", " b ", "c"}, nil},
+		{"tab separator with trim", Splitter{Separators: "\t", TrimSpace: true}, " a\t\tb ", []string{"a", "", "b"}, nil},
+
+		{"unterminated quote", csvSplitter, `a,"bc`, []string{"a"}, ErrQuote},
+		{"text after quote", csvSplitter, `"a"b,c`, nil, ErrQuote},
+		{"lone cr after quote", csvSplitter, "\"a\"\rb", nil, ErrQuote},
+		{"escape at end", Splitter{Escape: '\\'}, `a,b\`, []string{"a"}, ErrEscape},
+		{"field too long", Splitter{MaxFieldLen: 3}, "abc,abcd", []string{"abc"}, ErrFieldTooLong},
+		{"quoted field too long", Splitter{Quote: '"', MaxFieldLen: 3}, `"abcd"`, nil, ErrFieldTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.s.Split([]byte(tt.input), nil)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if !equalFields(got, tt.want) {
+				t.Fatalf("fields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAllocs(t *testing.T) {
+	line := []byte(`plain,"quoted, with ""quotes""",,last`)
+	data := make([]byte, len(line))
+	fields := make([][]byte, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		copy(data, line) // Split unquotes in place
+		var err error
+		if fields, err = csvSplitter.Split(data, fields[:0]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("Split allocated %v times per call", allocs)
+	}
+}
+
+// scanAll runs split over input one byte at a time, through a Scanner
+// whose buffer starts smaller than most tokens, so tokens cross buffer
+// boundaries and the split function sees partial input.
+func scanAll(input string, split bufio.SplitFunc) ([]string, error) {
+	sc := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(input)))
+	sc.Buffer(make([]byte, 2), 1<<10)
+	sc.Split(split)
+	var tokens []string
+	for sc.Scan() {
+		tokens = append(tokens, sc.Text())
+	}
+	return tokens, sc.Err()
+}
+
+func TestScanRecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		err   error
+	}{
+		{"empty", "", nil, nil},
+		{"lines", "a,b\nc,d\n", []string{"a,b", "c,d"}, nil},
+		{"no final newline", "a,b\nc", []string{"a,b", "c"}, nil},
+		{"crlf", "a\r\nb\r\n", []string{"a", "b"}, nil},
+		{"blank line", "a\n\nb\n", []string{"a", "", "b"}, nil},
+		{"quoted newline", "\"x\ny\",z\nnext\n", []string{"\"x\ny\",z", "next"}, nil},
+		{"long record", strings.Repeat("abc,", 50) + "\n", []string{strings.Repeat("abc,", 50)}, nil},
+		{"doubled quote at boundary", "\"a\"\"\",b\n", []string{"\"a\"\"\",b"}, nil},
+		{"unterminated quote", "a\n\"b\n", []string{"a"}, ErrQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scanAll(tt.input, csvSplitter.ScanRecords)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Fatalf("records = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestScanRecordsThenSplit checks that splitting the records of a stream
+// gives the same fields as splitting each record on its own.
+func TestScanRecordsThenSplit(t *testing.T) {
+	input := "id,name,note\r\n1,\"Smith, J\",\"says \"\"hi\"\"\"\r\n2,Lee,\"two\nlines\"\r\n3,,\n"
+	want := [][]string{
+		{"id", "name", "note"},
+		{"1", "Smith, J", `says "hi"`},
+		{"2", "Lee", "two\nlines"},
+		{"3", "", ""},
+	}
+	records, err := scanAll(input, csvSplitter.ScanRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(want) {
+		t.Fatalf("%d records, want %d: %q", len(records), len(want), records)
+	}
+	for i, r := range records {
+		fields, err := csvSplitter.Split([]byte(r), nil)
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !equalFields(fields, want[i]) {
+			t.Fatalf("record %d: fields %q, want %q", i, fields, want[i])
+		}
+	}
+}
+
+func TestFieldScanner(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Splitter
+		input string
+		want  []string
+		err   error
+	}{
+		{"empty", bareSplitter, "", nil, nil},
+		{"records", bareSplitter, "a,b\nc,d\n", []string{"a", "b", "c", "d"}, nil},
+		{"empty last field", bareSplitter, "a,b,", []string{"a", "b", ""}, nil},
+		{"empty field before newline", bareSplitter, "a,\nb", []string{"a", "", "b"}, nil},
+		{"crlf", bareSplitter, "a\r\nb\r\n", []string{"a", "b"}, nil},
+		{"quoted across records", csvSplitter, "x,\"d\ne\",\"\"\"\"\n", []string{"x", "d\ne", `"`}, nil},
+		{"several separators", Splitter{Separators: ";|"}, "a;b|c;", []string{"a", "b", "c", ""}, nil},
+		{"long field", bareSplitter, strings.Repeat("z", 100) + ",y", []string{strings.Repeat("z", 100), "y"}, nil},
+		{"unterminated quote", csvSplitter, "a,\"b", []string{"a"}, ErrQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scanAll(tt.input, tt.s.FieldScanner())
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Fatalf("fields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalFields(got [][]byte, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if string(got[i]) != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+// Package cache implements bounded in-memory caches: an LRU cache built on
+// a map and the linked list in structures/list, and an LFU cache built on
+// frequency buckets of the same lists.
+//
+// Both support per-entry expiry, eviction callbacks and hit/miss
+// statistics. Neither is safe for concurrent use on its own; wrap them
+// with Synchronized when they are shared between goroutines.
+package cache
+
+import "time"
+
+// A Cache maps keys to values, holding at most a fixed number of entries.
+type Cache[K comparable, V any] interface {
+	// Get returns the value cached for key and records the access.
+	// The boolean is false if key is not cached or its entry has expired.
+	Get(key K) (V, bool)
+	// Peek is like Get but has no side effects: it does not record the
+	// access, update statistics or remove an expired entry.
+	Peek(key K) (V, bool)
+	// Set caches value for key with the configured TTL, evicting an entry
+	// if the cache is full.
+	Set(key K, value V)
+	// SetWithTTL is like Set with a per-entry TTL; zero means no expiry.
+	SetWithTTL(key K, value V, ttl time.Duration)
+	// Delete removes key, reporting whether it was cached.
+	Delete(key K) bool
+	// DeleteExpired removes every expired entry and returns how many there were.
+	DeleteExpired() int
+	// Purge removes every entry.
+	Purge()
+	// Len returns the number of cached entries, including expired entries
+	// that have not been removed yet.
+	Len() int
+	// Stats returns the cache's counters.
+	Stats() Stats
+}
+
+// Reason describes why an entry left the cache.
+type Reason int
+
+const (
+	Evicted Reason = iota // removed to make room for a new entry
+	Expired               // its TTL passed
+	Deleted               // removed by Delete or Purge
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// Config configures a cache.
+type Config[K comparable, V any] struct {
+	// Capacity is the maximum number of entries. It must be positive.
+	Capacity int
+	// TTL is how long entries stored with Set remain valid.
+	// Zero means entries never expire.
+	TTL time.Duration
+	// OnEvict, if set, is called whenever an entry leaves the cache.
+	// It must not call back into the cache.
+	OnEvict func(key K, value V, reason Reason)
+	// Now returns the current time. It defaults to time.Now and can be
+	// replaced in tests to expire entries without sleeping.
+	Now func() time.Time
+}
+
+// Stats holds a cache's counters.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64 // entries removed to make room
+	Expirations uint64 // entries removed because their TTL passed
+}
+
+// HitRatio returns the fraction of lookups that were hits, or zero if there
+// have been none.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// core holds the state shared by the cache implementations.
+type core[K comparable, V any] struct {
+	Config[K, V]
+	stats Stats
+}
+
+func newCore[K comparable, V any](cfg Config[K, V]) core[K, V] {
+	if cfg.Capacity <= 0 {
+		panic("cache: capacity must be positive")
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return core[K, V]{Config: cfg}
+}
+
+// deadline returns the expiry time for an entry stored now with ttl, or
+// the zero time if it never expires.
+func (c *core[K, V]) deadline(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return c.Now().Add(ttl)
+}
+
+// expired reports whether an entry with the given expiry time has expired.
+func (c *core[K, V]) expired(expires time.Time) bool {
+	return !expires.IsZero() && !c.Now().Before(expires)
+}
+
+// removed updates the statistics and runs the callback for an entry that
+// has left the cache.
+func (c *core[K, V]) removed(key K, value V, reason Reason) {
+	switch reason {
+	case Evicted:
+		c.stats.Evictions++
+	case Expired:
+		c.stats.Expirations++
+	}
+	if c.OnEvict != nil {
+		c.OnEvict(key, value, reason)
+	}
+}
+
+// Stats returns the cache's counters.
+func (c *core[K, V]) Stats() Stats { return c.stats }
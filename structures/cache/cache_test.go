@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPeekHasNoSideEffects(t *testing.T) {
+	for name, newCache := range map[string]func(Config[string, int]) Cache[string, int]{
+		"LRU": func(cfg Config[string, int]) Cache[string, int] { return NewLRU(cfg) },
+		"LFU": func(cfg Config[string, int]) Cache[string, int] { return NewLFU(cfg) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			var removed []string
+			c := newCache(Config[string, int]{
+				Capacity: 2,
+				TTL:      time.Minute,
+				Now:      func() time.Time { return now },
+				OnEvict:  func(k string, _ int, _ Reason) { removed = append(removed, k) },
+			})
+			c.Set("a", 1)
+			c.Set("b", 2)
+
+			if v, ok := c.Peek("a"); !ok || v != 1 {
+				t.Fatalf("Peek(a) = %d, %v, want 1, true", v, ok)
+			}
+			if _, ok := c.Peek("x"); ok {
+				t.Fatal("Peek(x) reported ok")
+			}
+			now = now.Add(time.Hour)
+			if _, ok := c.Peek("a"); ok {
+				t.Fatal("Peek returned an expired entry")
+			}
+			if c.Len() != 2 || c.Stats() != (Stats{}) || removed != nil {
+				t.Fatalf("Peek had side effects: Len %d, Stats %+v, removed %v", c.Len(), c.Stats(), removed)
+			}
+
+			if n := c.DeleteExpired(); n != 2 || c.Stats().Expirations != 2 {
+				t.Fatalf("DeleteExpired = %d, Stats %+v", n, c.Stats())
+			}
+		})
+	}
+}
+
+// removal is an OnEvict call.
+type removal struct {
+	key    string
+	reason Reason
+}
+
+func recorder(log *[]removal) func(string, int, Reason) {
+	return func(k string, _ int, r Reason) { *log = append(*log, removal{k, r}) }
+}
+
+func TestLRUEvictionOrder(t *testing.T) {
+	var log []removal
+	c := NewLRU(Config[string, int]{Capacity: 3, OnEvict: recorder(&log)})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")     // order, most recent first: a c b
+	c.Set("b", 20) // b a c
+	c.Set("d", 4)  // evicts c: d b a
+	c.Get("a")     // a d b
+	c.Set("e", 5)  // evicts b: e a d
+
+	want := []removal{{"c", Evicted}, {"b", Evicted}}
+	if !slices.Equal(log, want) {
+		t.Fatalf("removed %v, want %v", log, want)
+	}
+	for k, v := range map[string]int{"a": 1, "d": 4, "e": 5} {
+		if got, ok := c.Peek(k); !ok || got != v {
+			t.Errorf("Peek(%s) = %d, %v, want %d, true", k, got, ok, v)
+		}
+	}
+}
+
+func TestLFUEviction(t *testing.T) {
+	var log []removal
+	c := NewLFU(Config[string, int]{Capacity: 3, OnEvict: recorder(&log)})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+	c.Get("a")    // a:3
+	c.Get("b")    // b:2, c:1
+	c.Set("d", 4) // evicts c, the least frequently used
+	// b and d are tied at 2 once d is read; b was used less recently.
+	c.Get("d")
+	c.Set("e", 5)
+
+	want := []removal{{"c", Evicted}, {"b", Evicted}}
+	if !slices.Equal(log, want) {
+		t.Fatalf("removed %v, want %v", log, want)
+	}
+
+	// Among entries used once, the least recently set goes first.
+	c.Purge()
+	log = nil
+	for _, k := range []string{"x", "y", "z", "w"} {
+		c.Set(k, 0)
+	}
+	if want := []removal{{"x", Evicted}}; !slices.Equal(log, want) {
+		t.Fatalf("tie-break removed %v, want %v", log, want)
+	}
+}
+
+func TestOnEvictReasons(t *testing.T) {
+	for name, newCache := range map[string]func(Config[string, int]) Cache[string, int]{
+		"LRU": func(cfg Config[string, int]) Cache[string, int] { return NewLRU(cfg) },
+		"LFU": func(cfg Config[string, int]) Cache[string, int] { return NewLFU(cfg) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			var log []removal
+			c := newCache(Config[string, int]{
+				Capacity: 2,
+				Now:      func() time.Time { return now },
+				OnEvict:  recorder(&log),
+			})
+			c.SetWithTTL("short", 1, time.Second)
+			c.Set("keep", 2)
+			now = now.Add(2 * time.Second)
+			if _, ok := c.Get("short"); ok {
+				t.Fatal("Get returned an expired entry")
+			}
+			c.Set("x", 3)
+			c.Set("y", 4) // full: evicts keep or x
+			if !c.Delete("y") || c.Delete("y") {
+				t.Fatal("Delete did not report the entry exactly once")
+			}
+			c.Purge()
+
+			reasons := make([]Reason, len(log))
+			for i, r := range log {
+				reasons[i] = r.reason
+			}
+			want := []Reason{Expired, Evicted, Deleted, Deleted}
+			if !slices.Equal(reasons, want) {
+				t.Fatalf("reasons %v, want %v", reasons, want)
+			}
+			if s := c.Stats(); s.Evictions != 1 || s.Expirations != 1 {
+				t.Fatalf("Stats = %+v, want 1 eviction and 1 expiration", s)
+			}
+			if c.Len() != 0 {
+				t.Fatalf("Len = %d after Purge", c.Len())
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := NewLRU(Config[int, int]{Capacity: 2})
+	c.Set(1, 1)
+	c.Get(1)
+	c.Get(1)
+	c.Get(2)
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("Stats = %+v, want 2 hits and 1 miss", s)
+	}
+	if r := s.HitRatio(); r != 2.0/3 {
+		t.Fatalf("HitRatio = %v, want 2/3", r)
+	}
+	if r := (Stats{}).HitRatio(); r != 0 {
+		t.Fatalf("HitRatio with no lookups = %v", r)
+	}
+}
+
+// TestSynchronized is meant to be run with -race.
+func TestSynchronized(t *testing.T) {
+	for name, c := range map[string]Cache[string, int]{
+		"LRU": Synchronized[string, int](NewLRU(Config[string, int]{Capacity: 16})),
+		"LFU": Synchronized[string, int](NewLFU(Config[string, int]{Capacity: 16})),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for g := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 1000 {
+						k := fmt.Sprint((g + i) % 32)
+						c.Set(k, i)
+						c.Get(k)
+						c.Peek(k)
+						if i%100 == 0 {
+							c.Delete(k)
+							c.DeleteExpired()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			if n := c.Len(); n > 16 {
+				t.Fatalf("Len = %d, over capacity", n)
+			}
+			if s := c.Stats(); s.Hits+s.Misses != 8000 {
+				t.Fatalf("Stats count %d lookups, want 8000", s.Hits+s.Misses)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/ops2go/go-fundamentals/structures/list"
+)
+
+// An LFU is a cache that evicts the least frequently used entry when full,
+// breaking ties by evicting the least recently used of those entries.
+//
+// Entries are grouped into buckets of equal access count, kept in
+// increasing count order, so every operation is O(1).
+type LFU[K comparable, V any] struct {
+	core[K, V]
+	items   map[K]*lfuEntry[K, V]
+	buckets list.List[*lfuBucket[K, V]] // lowest count at the front
+}
+
+type lfuBucket[K comparable, V any] struct {
+	count   uint64
+	entries list.List[*lfuEntry[K, V]] // most recently used at the front
+}
+
+type lfuEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+	bucket  *list.Element[*lfuBucket[K, V]]
+	elem    *list.Element[*lfuEntry[K, V]] // this entry within its bucket
+}
+
+// NewLFU returns an empty LFU cache configured by cfg.
+// It panics if cfg.Capacity is not positive.
+func NewLFU[K comparable, V any](cfg Config[K, V]) *LFU[K, V] {
+	return &LFU[K, V]{
+		core:  newCore(cfg),
+		items: make(map[K]*lfuEntry[K, V], cfg.Capacity),
+	}
+}
+
+// Get returns the value cached for key and increments its access count.
+func (c *LFU[K, V]) Get(key K) (V, bool) {
+	e, ok := c.lookup(key)
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.touch(e)
+	return e.value, true
+}
+
+// Peek returns the value cached for key without changing its access count.
+// An expired entry is reported as missing but stays in the cache until
+// Get, eviction or DeleteExpired removes it.
+func (c *LFU[K, V]) Peek(key K) (V, bool) {
+	e, ok := c.items[key]
+	if !ok || c.expired(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set caches value for key. Replacing the value of a cached key counts as
+// an access to it; a new key starts with an access count of one.
+func (c *LFU[K, V]) Set(key K, value V) { c.SetWithTTL(key, value, c.TTL) }
+
+// SetWithTTL is like Set with a per-entry TTL; zero means no expiry.
+func (c *LFU[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if e, ok := c.items[key]; ok {
+		e.value = value
+		e.expires = c.deadline(ttl)
+		c.touch(e)
+		return
+	}
+	if len(c.items) >= c.Capacity {
+		c.evict()
+	}
+
+	e := &lfuEntry[K, V]{key: key, value: value, expires: c.deadline(ttl)}
+	first := c.buckets.Front()
+	if first == nil || first.Value.count != 1 {
+		first = c.buckets.PushFront(&lfuBucket[K, V]{count: 1})
+	}
+	e.bucket = first
+	e.elem = first.Value.entries.PushFront(e)
+	c.items[key] = e
+}
+
+// Delete removes key, reporting whether it was cached.
+func (c *LFU[K, V]) Delete(key K) bool {
+	e, ok := c.items[key]
+	if ok {
+		c.remove(e, Deleted)
+	}
+	return ok
+}
+
+// DeleteExpired removes every expired entry and returns how many there were.
+func (c *LFU[K, V]) DeleteExpired() int {
+	n := 0
+	for _, e := range c.items {
+		if c.expired(e.expires) {
+			c.remove(e, Expired)
+			n++
+		}
+	}
+	return n
+}
+
+// Purge removes every entry.
+func (c *LFU[K, V]) Purge() {
+	for _, e := range c.items {
+		c.remove(e, Deleted)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *LFU[K, V]) Len() int { return len(c.items) }
+
+// lookup finds the live entry for key, removing it instead if it has expired.
+func (c *LFU[K, V]) lookup(key K) (*lfuEntry[K, V], bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if c.expired(e.expires) {
+		c.remove(e, Expired)
+		return nil, false
+	}
+	return e, true
+}
+
+// touch moves e into the bucket for its next access count.
+func (c *LFU[K, V]) touch(e *lfuEntry[K, V]) {
+	cur := e.bucket
+	next := cur.Next()
+	if next == nil || next.Value.count != cur.Value.count+1 {
+		next = c.buckets.InsertAfter(&lfuBucket[K, V]{count: cur.Value.count + 1}, cur)
+	}
+	c.unlink(e)
+	e.bucket = next
+	e.elem = next.Value.entries.PushFront(e)
+}
+
+// evict removes the least recently used entry among those with the lowest
+// access count to make room for a new one.
+// An entry that had already expired is reported as such.
+func (c *LFU[K, V]) evict() {
+	e := c.buckets.Front().Value.entries.Back().Value
+	if c.expired(e.expires) {
+		c.remove(e, Expired)
+		return
+	}
+	c.remove(e, Evicted)
+}
+
+func (c *LFU[K, V]) remove(e *lfuEntry[K, V], reason Reason) {
+	c.unlink(e)
+	delete(c.items, e.key)
+	c.removed(e.key, e.value, reason)
+}
+
+// unlink takes e out of its bucket, dropping the bucket if it is left empty.
+func (c *LFU[K, V]) unlink(e *lfuEntry[K, V]) {
+	b := e.bucket.Value
+	b.entries.Remove(e.elem)
+	if b.entries.Len() == 0 {
+		c.buckets.Remove(e.bucket)
+	}
+	e.bucket, e.elem = nil, nil
+}
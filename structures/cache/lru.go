@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/ops2go/go-fundamentals/structures/list"
+)
+
+// An LRU is a cache that evicts the least recently used entry when full.
+type LRU[K comparable, V any] struct {
+	core[K, V]
+	items map[K]*list.Element[lruEntry[K, V]]
+	order list.List[lruEntry[K, V]] // most recently used at the front
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU returns an empty LRU cache configured by cfg.
+// It panics if cfg.Capacity is not positive.
+func NewLRU[K comparable, V any](cfg Config[K, V]) *LRU[K, V] {
+	return &LRU[K, V]{
+		core:  newCore(cfg),
+		items: make(map[K]*list.Element[lruEntry[K, V]], cfg.Capacity),
+	}
+}
+
+// Get returns the value cached for key and marks it most recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	e, ok := c.lookup(key)
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(e)
+	return e.Value.value, true
+}
+
+// Peek returns the value cached for key without changing its recency.
+// An expired entry is reported as missing but stays in the cache until
+// Get, eviction or DeleteExpired removes it.
+func (c *LRU[K, V]) Peek(key K) (V, bool) {
+	e, ok := c.items[key]
+	if !ok || c.expired(e.Value.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.Value.value, true
+}
+
+// Set caches value for key and marks it most recently used.
+func (c *LRU[K, V]) Set(key K, value V) { c.SetWithTTL(key, value, c.TTL) }
+
+// SetWithTTL is like Set with a per-entry TTL; zero means no expiry.
+func (c *LRU[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	entry := lruEntry[K, V]{key: key, value: value, expires: c.deadline(ttl)}
+	if e, ok := c.items[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	if len(c.items) >= c.Capacity {
+		c.evict()
+	}
+	c.items[key] = c.order.PushFront(entry)
+}
+
+// Delete removes key, reporting whether it was cached.
+func (c *LRU[K, V]) Delete(key K) bool {
+	e, ok := c.items[key]
+	if ok {
+		c.remove(e, Deleted)
+	}
+	return ok
+}
+
+// DeleteExpired removes every expired entry and returns how many there were.
+func (c *LRU[K, V]) DeleteExpired() int {
+	n := 0
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+		if c.expired(e.Value.expires) {
+			c.remove(e, Expired)
+			n++
+		}
+		e = next
+	}
+	return n
+}
+
+// Purge removes every entry.
+func (c *LRU[K, V]) Purge() {
+	for e := c.order.Back(); e != nil; e = c.order.Back() {
+		c.remove(e, Deleted)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *LRU[K, V]) Len() int { return len(c.items) }
+
+// lookup finds the live entry for key, removing it instead if it has expired.
+func (c *LRU[K, V]) lookup(key K) (*list.Element[lruEntry[K, V]], bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if c.expired(e.Value.expires) {
+		c.remove(e, Expired)
+		return nil, false
+	}
+	return e, true
+}
+
+// evict removes the least recently used entry to make room for a new one.
+// An entry that had already expired is reported as such.
+func (c *LRU[K, V]) evict() {
+	e := c.order.Back()
+	if c.expired(e.Value.expires) {
+		c.remove(e, Expired)
+		return
+	}
+	c.remove(e, Evicted)
+}
+
+func (c *LRU[K, V]) remove(e *list.Element[lruEntry[K, V]], reason Reason) {
+	c.order.Remove(e)
+	delete(c.items, e.Value.key)
+	c.removed(e.Value.key, e.Value.value, reason)
+}
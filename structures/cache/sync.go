@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Synchronized returns a Cache that serializes all calls to c, making it
+// safe for concurrent use. Every call, including Get, takes an exclusive
+// lock, since lookups reorder the underlying cache.
+func Synchronized[K comparable, V any](c Cache[K, V]) Cache[K, V] {
+	return &syncCache[K, V]{c: c}
+}
+
+type syncCache[K comparable, V any] struct {
+	mu sync.Mutex
+	c  Cache[K, V]
+}
+
+func (s *syncCache[K, V]) Get(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Get(key)
+}
+
+func (s *syncCache[K, V]) Peek(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Peek(key)
+}
+
+func (s *syncCache[K, V]) Set(key K, value V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Set(key, value)
+}
+
+func (s *syncCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.SetWithTTL(key, value, ttl)
+}
+
+func (s *syncCache[K, V]) Delete(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Delete(key)
+}
+
+func (s *syncCache[K, V]) DeleteExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.DeleteExpired()
+}
+
+func (s *syncCache[K, V]) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Purge()
+}
+
+func (s *syncCache[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Len()
+}
+
+func (s *syncCache[K, V]) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Stats()
+}
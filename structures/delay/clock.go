@@ -0,0 +1,120 @@
+package delay
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time and creates timers. Queues use it for every
+// time-related decision so tests can substitute a ManualClock for the real
+// one.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// AfterTime returns a channel that receives the current time once the
+	// clock reaches t, and a function that stops the timer and releases
+	// its resources. Stopping a timer that has fired is a no-op.
+	AfterTime(t time.Time) (<-chan time.Time, func())
+}
+
+// RealClock is the Clock backed by the time package.
+type RealClock struct{}
+
+// Now returns time.Now().
+func (RealClock) Now() time.Time { return time.Now() }
+
+// AfterTime returns the channel and Stop method of a time.Timer that fires
+// at t.
+func (RealClock) AfterTime(t time.Time) (<-chan time.Time, func()) {
+	timer := time.NewTimer(time.Until(t))
+	return timer.C, func() { timer.Stop() }
+}
+
+// A ManualClock is a Clock whose time only moves when Advance or Set is
+// called. It is safe for concurrent use.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewManualClock returns a ManualClock set to now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterTime returns a channel that receives the clock's time once the
+// clock has been moved to t or later. The stop function removes the
+// pending timer.
+func (c *ManualClock) AfterTime(t time.Time) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if !t.After(c.now) {
+		ch <- c.now
+		return ch, func() {}
+	}
+	w := &waiter{at: t, c: ch}
+	c.waiters = append(c.waiters, w)
+	return ch, func() { c.stop(w) }
+}
+
+// Waiters returns the number of timers that have neither fired nor been
+// stopped.
+func (c *ManualClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (c *ManualClock) stop(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := slices.Index(c.waiters, w); i >= 0 {
+		c.waiters = slices.Delete(c.waiters, i, i+1)
+	}
+}
+
+// Advance moves the clock forward by d and fires every timer whose time
+// has been reached.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing every timer whose time has been
+// reached. Setting the clock backwards fires nothing.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(t)
+}
+
+// set updates the time and fires due waiters. c.mu must be held.
+func (c *ManualClock) set(t time.Time) {
+	c.now = t
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+		w.c <- t
+	}
+	clear(c.waiters[len(pending):])
+	c.waiters = pending
+}
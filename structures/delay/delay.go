@@ -0,0 +1,154 @@
+// Package delay implements a delay queue: a time-ordered min-heap whose
+// values only become available once their ready-at time has passed.
+//
+// It is useful for scheduling retries and timeouts. All time decisions go
+// through a Clock, so tests can drive the queue with a ManualClock instead
+// of sleeping.
+package delay
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// A Queue holds values until their ready-at time. It is safe for
+// concurrent use.
+type Queue[T any] struct {
+	mu    sync.Mutex
+	clock Clock
+	h     entryHeap[T]
+	seq   uint64
+	// changed is closed, and replaced, whenever a value is pushed so that
+	// waiters can re-check the earliest deadline.
+	changed chan struct{}
+}
+
+// New returns an empty queue driven by clock. A nil clock means RealClock.
+func New[T any](clock Clock) *Queue[T] {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &Queue[T]{clock: clock, changed: make(chan struct{})}
+}
+
+// Len returns the number of values in the queue, ready or not.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.h)
+}
+
+// Push schedules v to become available at time at.
+// Values with equal ready-at times are taken in the order they were pushed.
+func (q *Queue[T]) Push(v T, at time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	heap.Push(&q.h, entry[T]{value: v, at: at, seq: q.seq})
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// PushAfter schedules v to become available once d has elapsed on the
+// queue's clock.
+func (q *Queue[T]) PushAfter(v T, d time.Duration) {
+	q.Push(v, q.clock.Now().Add(d))
+}
+
+// Next returns the earliest ready-at time in the queue.
+// The boolean is false if the queue is empty.
+func (q *Queue[T]) Next() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.h) == 0 {
+		return time.Time{}, false
+	}
+	return q.h[0].at, true
+}
+
+// Take removes and returns the earliest value whose ready-at time has
+// passed. The boolean is false if no value is ready yet.
+func (q *Queue[T]) Take() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	v, ok, _ := q.take()
+	return v, ok
+}
+
+// Wait removes and returns the earliest value, blocking until its ready-at
+// time has passed. It returns the context's error if ctx is done first.
+func (q *Queue[T]) Wait(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		v, ok, at := q.take()
+		changed := q.changed
+		q.mu.Unlock()
+		if ok {
+			return v, nil
+		}
+
+		// The timer is set for the head's ready-at time itself, so a clock
+		// that moves before it is registered cannot push the deadline back.
+		var timer <-chan time.Time
+		stop := func() {}
+		if !at.IsZero() {
+			timer, stop = q.clock.AfterTime(at)
+		}
+		select {
+		case <-timer:
+		case <-changed:
+		case <-ctx.Done():
+			stop()
+			var zero T
+			return zero, ctx.Err()
+		}
+		stop()
+	}
+}
+
+// take pops the head value if it is ready. Otherwise it returns the head's
+// ready-at time, or the zero time if the queue is empty. q.mu must be held.
+func (q *Queue[T]) take() (v T, ok bool, at time.Time) {
+	if len(q.h) == 0 {
+		return v, false, time.Time{}
+	}
+	if at := q.h[0].at; at.After(q.clock.Now()) {
+		return v, false, at
+	}
+	return heap.Pop(&q.h).(entry[T]).value, true, time.Time{}
+}
+
+type entry[T any] struct {
+	value T
+	at    time.Time
+	seq   uint64 // insertion order, breaks ties between equal times
+}
+
+// An entryHeap is a min-heap of entries ordered by ready-at time.
+type entryHeap[T any] []entry[T]
+
+func (h entryHeap[T]) Len() int { return len(h) }
+
+func (h entryHeap[T]) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h entryHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap[T]) Push(x any) {
+	*h = append(*h, x.(entry[T]))
+}
+
+func (h *entryHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = entry[T]{} // avoid retaining the value
+	*h = old[0 : n-1]
+	return x
+}
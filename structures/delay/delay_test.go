@@ -0,0 +1,96 @@
+package delay
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestWaitManualClock(t *testing.T) {
+	clock := NewManualClock(epoch)
+	q := New[string](clock)
+	q.PushAfter("b", 2*time.Second)
+	q.PushAfter("a", time.Second)
+
+	got := make(chan string)
+	go func() {
+		for range 2 {
+			v, err := q.Wait(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			got <- v
+		}
+	}()
+
+	// Each Advance may land before or after Wait registers its timer; the
+	// timer is for an absolute time, so the order does not matter.
+	for _, want := range []string{"a", "b"} {
+		clock.Advance(time.Second)
+		select {
+		case v := <-got:
+			if v != want {
+				t.Fatalf("Wait = %q, want %q", v, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Wait for %q did not return", want)
+		}
+	}
+}
+
+func TestWaitReleasesTimers(t *testing.T) {
+	clock := NewManualClock(epoch)
+	q := New[int](clock)
+	q.PushAfter(1, time.Hour)
+
+	for range 100 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if _, err := q.Wait(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("Wait = %v, want %v", err, context.DeadlineExceeded)
+		}
+		cancel()
+	}
+	if n := clock.Waiters(); n != 0 {
+		t.Fatalf("%d timers left after cancelled waits", n)
+	}
+
+	// Pushes wake Wait through changed; the superseded timer is released too.
+	done := make(chan int)
+	go func() {
+		v, _ := q.Wait(context.Background())
+		done <- v
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	q.Push(2, epoch)
+	if v := <-done; v != 2 {
+		t.Fatalf("Wait = %d, want 2", v)
+	}
+	if n := clock.Waiters(); n != 0 {
+		t.Fatalf("%d timers left after a wake-up by Push", n)
+	}
+}
+
+func TestTakeOrder(t *testing.T) {
+	clock := NewManualClock(epoch)
+	q := New[int](clock)
+	q.Push(1, epoch.Add(time.Second))
+	q.Push(2, epoch.Add(time.Second))
+	q.Push(0, epoch)
+
+	if v, ok := q.Take(); !ok || v != 0 {
+		t.Fatalf("Take = %d, %v, want 0, true", v, ok)
+	}
+	if _, ok := q.Take(); ok {
+		t.Fatal("Take returned a value before its time")
+	}
+	clock.Advance(time.Second)
+	for _, want := range []int{1, 2} {
+		if v, ok := q.Take(); !ok || v != want {
+			t.Fatalf("Take = %d, %v, want %d, true", v, ok, want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package dheap implements an indexed d-ary heap.
+//
+// Unlike the IntHeap and PriorityQueue examples in structures, values are
+// addressed by a comparable key, so the priority of any key can be raised or
+// lowered in O(log n) without holding on to an item handle. This is the
+// operation graph algorithms such as Dijkstra and Prim rely on; see
+// structures/graph for both.
+//
+// A larger arity makes the tree shallower, trading cheaper sift-ups
+// (DecreaseKey, Push) for more comparisons per sift-down (Pop).
+package dheap
+
+// A Heap is a d-ary min-heap of keys ordered by their priorities.
+// The zero Heap is not usable; create heaps with New.
+type Heap[K comparable, P any] struct {
+	d     int
+	nodes []node[K, P]
+	index map[K]int // position of each key in nodes
+	less  func(a, b P) bool
+}
+
+type node[K comparable, P any] struct {
+	key  K
+	prio P
+}
+
+// New returns an empty heap with the given arity, ordered by less.
+// The key whose priority is less than all others is at the head.
+// New panics if arity is less than 2.
+func New[K comparable, P any](arity int, less func(a, b P) bool) *Heap[K, P] {
+	if arity < 2 {
+		panic("dheap: arity must be at least 2")
+	}
+	return &Heap[K, P]{d: arity, index: make(map[K]int), less: less}
+}
+
+// Len returns the number of keys in the heap.
+func (h *Heap[K, P]) Len() int { return len(h.nodes) }
+
+// Contains reports whether k is in the heap.
+func (h *Heap[K, P]) Contains(k K) bool {
+	_, ok := h.index[k]
+	return ok
+}
+
+// Priority returns the priority of k. The boolean is false if k is not in
+// the heap.
+func (h *Heap[K, P]) Priority(k K) (P, bool) {
+	i, ok := h.index[k]
+	if !ok {
+		var zero P
+		return zero, false
+	}
+	return h.nodes[i].prio, true
+}
+
+// Push adds k with priority p. It reports false, and changes nothing, if k
+// is already in the heap; use Update to change an existing priority.
+func (h *Heap[K, P]) Push(k K, p P) bool {
+	if _, ok := h.index[k]; ok {
+		return false
+	}
+	h.nodes = append(h.nodes, node[K, P]{key: k, prio: p})
+	i := len(h.nodes) - 1
+	h.index[k] = i
+	h.up(i)
+	return true
+}
+
+// Peek returns the key at the head of the heap and its priority without
+// removing it. The boolean is false if the heap is empty.
+func (h *Heap[K, P]) Peek() (K, P, bool) {
+	if len(h.nodes) == 0 {
+		var k K
+		var p P
+		return k, p, false
+	}
+	return h.nodes[0].key, h.nodes[0].prio, true
+}
+
+// Pop removes and returns the key at the head of the heap and its priority.
+// The boolean is false if the heap is empty.
+func (h *Heap[K, P]) Pop() (K, P, bool) {
+	if len(h.nodes) == 0 {
+		var k K
+		var p P
+		return k, p, false
+	}
+	n := h.removeAt(0)
+	return n.key, n.prio, true
+}
+
+// Remove removes k from the heap and returns its priority.
+// The boolean is false if k is not in the heap.
+func (h *Heap[K, P]) Remove(k K) (P, bool) {
+	i, ok := h.index[k]
+	if !ok {
+		var zero P
+		return zero, false
+	}
+	return h.removeAt(i).prio, true
+}
+
+// DecreaseKey lowers the priority of k to p, moving it towards the head.
+// It reports false, and changes nothing, if k is not in the heap or p is
+// greater than its current priority.
+func (h *Heap[K, P]) DecreaseKey(k K, p P) bool {
+	i, ok := h.index[k]
+	if !ok || h.less(h.nodes[i].prio, p) {
+		return false
+	}
+	h.nodes[i].prio = p
+	h.up(i)
+	return true
+}
+
+// IncreaseKey raises the priority of k to p, moving it away from the head.
+// It reports false, and changes nothing, if k is not in the heap or p is
+// less than its current priority.
+func (h *Heap[K, P]) IncreaseKey(k K, p P) bool {
+	i, ok := h.index[k]
+	if !ok || h.less(p, h.nodes[i].prio) {
+		return false
+	}
+	h.nodes[i].prio = p
+	h.down(i)
+	return true
+}
+
+// Update sets the priority of k to p, adding k if it is not in the heap.
+func (h *Heap[K, P]) Update(k K, p P) {
+	i, ok := h.index[k]
+	if !ok {
+		h.Push(k, p)
+		return
+	}
+	h.nodes[i].prio = p
+	if !h.up(i) {
+		h.down(i)
+	}
+}
+
+// removeAt removes and returns the node at position i.
+func (h *Heap[K, P]) removeAt(i int) node[K, P] {
+	n := h.nodes[i]
+	last := len(h.nodes) - 1
+	if i != last {
+		h.swap(i, last)
+	}
+	h.nodes[last] = node[K, P]{} // avoid retaining the key
+	h.nodes = h.nodes[:last]
+	delete(h.index, n.key)
+	if i != last && !h.up(i) {
+		h.down(i)
+	}
+	return n
+}
+
+// up moves the node at i towards the root until its parent is no greater.
+// It reports whether the node moved.
+func (h *Heap[K, P]) up(i int) bool {
+	start := i
+	for i > 0 {
+		parent := (i - 1) / h.d
+		if !h.less(h.nodes[i].prio, h.nodes[parent].prio) {
+			break
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+	return i != start
+}
+
+// down moves the node at i away from the root until none of its children
+// is smaller.
+func (h *Heap[K, P]) down(i int) {
+	n := len(h.nodes)
+	for {
+		first := h.d*i + 1
+		if first >= n {
+			return
+		}
+		smallest := first
+		for c := first + 1; c < first+h.d && c < n; c++ {
+			if h.less(h.nodes[c].prio, h.nodes[smallest].prio) {
+				smallest = c
+			}
+		}
+		if !h.less(h.nodes[smallest].prio, h.nodes[i].prio) {
+			return
+		}
+		h.swap(i, smallest)
+		i = smallest
+	}
+}
+
+func (h *Heap[K, P]) swap(i, j int) {
+	h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i]
+	h.index[h.nodes[i].key] = i
+	h.index[h.nodes[j].key] = j
+}
@@ -0,0 +1,101 @@
+package dheap
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestHeapRandom(t *testing.T) {
+	for _, arity := range []int{2, 3, 4, 8} {
+		r := rand.New(rand.NewPCG(uint64(arity), 1))
+		h := New[int](arity, func(a, b int) bool { return a < b })
+		want := make(map[int]int) // key -> priority
+		for range 5000 {
+			k, p := r.IntN(50), r.IntN(1000)
+			switch r.IntN(6) {
+			case 0:
+				_, had := want[k]
+				if h.Push(k, p) == had {
+					t.Fatalf("d=%d: Push(%d) = %v with key present %v", arity, k, !had, had)
+				}
+				if !had {
+					want[k] = p
+				}
+			case 1:
+				old, had := want[k]
+				ok := h.DecreaseKey(k, p)
+				if ok != (had && p <= old) {
+					t.Fatalf("d=%d: DecreaseKey(%d, %d) = %v, old %d present %v", arity, k, p, ok, old, had)
+				}
+				if ok {
+					want[k] = p
+				}
+			case 2:
+				old, had := want[k]
+				ok := h.IncreaseKey(k, p)
+				if ok != (had && p >= old) {
+					t.Fatalf("d=%d: IncreaseKey(%d, %d) = %v, old %d present %v", arity, k, p, ok, old, had)
+				}
+				if ok {
+					want[k] = p
+				}
+			case 3:
+				h.Update(k, p)
+				want[k] = p
+			case 4:
+				p, ok := h.Remove(k)
+				if old, had := want[k]; ok != had || ok && p != old {
+					t.Fatalf("d=%d: Remove(%d) = %d, %v, want %d, %v", arity, k, p, ok, old, had)
+				}
+				delete(want, k)
+			case 5:
+				k, p, ok := h.Pop()
+				if !ok {
+					if len(want) != 0 {
+						t.Fatalf("d=%d: Pop failed with %d keys", arity, len(want))
+					}
+					continue
+				}
+				for _, q := range want {
+					if q < p {
+						t.Fatalf("d=%d: Pop = %d, %d, but %d is smaller", arity, k, p, q)
+					}
+				}
+				if want[k] != p {
+					t.Fatalf("d=%d: Pop = %d, %d, want priority %d", arity, k, p, want[k])
+				}
+				delete(want, k)
+			}
+			if h.Len() != len(want) {
+				t.Fatalf("d=%d: Len = %d, want %d", arity, h.Len(), len(want))
+			}
+		}
+
+		var got, sorted []int
+		for _, p := range want {
+			sorted = append(sorted, p)
+		}
+		slices.SortFunc(sorted, cmp.Compare)
+		for h.Len() > 0 {
+			k, p, _ := h.Pop()
+			if h.Contains(k) {
+				t.Fatalf("d=%d: popped %d still contained", arity, k)
+			}
+			got = append(got, p)
+		}
+		if !slices.Equal(got, sorted) {
+			t.Fatalf("d=%d: drained %v, want %v", arity, got, sorted)
+		}
+	}
+}
+
+func TestNewPanicsOnSmallArity(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("New(1) did not panic")
+		}
+	}()
+	New[int](1, func(a, b int) bool { return a < b })
+}
@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"math"
+
+	"github.com/ops2go/go-fundamentals/structures/dheap"
+)
+
+// Dijkstra computes the shortest distance from src to every vertex of g.
+// Edge weights must be non-negative.
+//
+// dist[v] is math.Inf(1) for vertices that cannot be reached. prev[v] is
+// the vertex before v on a shortest path from src, or -1 for src and for
+// unreachable vertices; pass it to Path to recover the route.
+func Dijkstra(g *Graph, src int) (dist []float64, prev []int) {
+	dist = make([]float64, g.Len())
+	prev = make([]int, g.Len())
+	for v := range dist {
+		dist[v] = math.Inf(1)
+		prev[v] = -1
+	}
+	dist[src] = 0
+
+	// The heap holds the frontier: vertices reached but not yet settled,
+	// keyed by their best known distance.
+	h := dheap.New[int](heapArity, func(a, b float64) bool { return a < b })
+	h.Push(src, 0)
+	for h.Len() > 0 {
+		u, d, _ := h.Pop()
+		for _, e := range g.Edges(u) {
+			nd := d + e.Weight
+			if nd >= dist[e.To] {
+				continue
+			}
+			dist[e.To] = nd
+			prev[e.To] = u
+			if !h.DecreaseKey(e.To, nd) {
+				h.Push(e.To, nd)
+			}
+		}
+	}
+	return dist, prev
+}
+
+// Path returns the vertices on a shortest path from the source to dst,
+// using prev as returned by Dijkstra. If dst is unreachable the result is
+// just dst itself, so check dist[dst] before relying on it.
+func Path(prev []int, dst int) []int {
+	var path []int
+	for v := dst; v != -1; v = prev[v] {
+		path = append(path, v)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,53 @@
+package graph_test
+
+import (
+	"fmt"
+
+	"github.com/ops2go/go-fundamentals/structures/graph"
+)
+
+func ExampleDijkstra() {
+	// 0 --1--> 1 --2--> 3
+	// |                 ^
+	// +------4--> 2 -1--+
+	g := graph.New(5)
+	g.AddEdge(0, 1, 1)
+	g.AddEdge(1, 3, 2)
+	g.AddEdge(0, 2, 4)
+	g.AddEdge(2, 3, 1)
+	g.AddEdge(1, 2, 5)
+
+	dist, prev := graph.Dijkstra(g, 0)
+	for v, d := range dist {
+		fmt.Println(v, d, graph.Path(prev, v))
+	}
+	// Output:
+	// 0 0 [0]
+	// 1 1 [0 1]
+	// 2 4 [0 2]
+	// 3 3 [0 1 3]
+	// 4 +Inf [4]
+}
+
+func ExamplePrim() {
+	g := graph.New(6)
+	g.AddUndirected(0, 1, 4)
+	g.AddUndirected(0, 2, 1)
+	g.AddUndirected(1, 2, 2)
+	g.AddUndirected(1, 3, 5)
+	g.AddUndirected(2, 3, 8)
+	// 4 and 5 form a second component.
+	g.AddUndirected(4, 5, 3)
+
+	tree, total := graph.Prim(g)
+	for _, e := range tree {
+		fmt.Println(e.From, "-", e.To, e.Weight)
+	}
+	fmt.Println("total", total)
+	// Output:
+	// 0 - 2 1
+	// 2 - 1 2
+	// 1 - 3 5
+	// 4 - 5 3
+	// total 11
+}
@@ -0,0 +1,45 @@
+// Package graph provides a small weighted graph and worked examples of
+// shortest paths (Dijkstra) and minimum spanning trees (Prim) built on the
+// indexed heap in structures/dheap.
+package graph
+
+// heapArity is the arity of the heaps used by the algorithms. Both perform
+// many more DecreaseKey calls than Pops on dense graphs, which favours a
+// shallower tree than a binary heap.
+const heapArity = 4
+
+// An Edge is a weighted connection from one vertex to another.
+type Edge struct {
+	From, To int
+	Weight   float64
+}
+
+// A Graph is a weighted graph over the vertices 0 to n-1, stored as
+// adjacency lists.
+type Graph struct {
+	adj [][]Edge
+}
+
+// New returns a graph with n vertices and no edges.
+func New(n int) *Graph {
+	return &Graph{adj: make([][]Edge, n)}
+}
+
+// Len returns the number of vertices in the graph.
+func (g *Graph) Len() int { return len(g.adj) }
+
+// AddEdge adds a directed edge from u to v.
+// It panics if either vertex is out of range.
+func (g *Graph) AddEdge(u, v int, weight float64) {
+	_ = g.adj[v] // bounds check v before mutating anything
+	g.adj[u] = append(g.adj[u], Edge{From: u, To: v, Weight: weight})
+}
+
+// AddUndirected adds edges in both directions between u and v.
+func (g *Graph) AddUndirected(u, v int, weight float64) {
+	g.AddEdge(u, v, weight)
+	g.AddEdge(v, u, weight)
+}
+
+// Edges returns the edges leaving u. The slice must not be modified.
+func (g *Graph) Edges(u int) []Edge { return g.adj[u] }
@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func randomGraph(r *rand.Rand, n, m int, undirected bool) *Graph {
+	g := New(n)
+	for range m {
+		u, v, w := r.IntN(n), r.IntN(n), float64(r.IntN(20))
+		if undirected {
+			g.AddUndirected(u, v, w)
+		} else {
+			g.AddEdge(u, v, w)
+		}
+	}
+	return g
+}
+
+// bellmanFord is the reference for Dijkstra.
+func bellmanFord(g *Graph, src int) []float64 {
+	dist := make([]float64, g.Len())
+	for v := range dist {
+		dist[v] = math.Inf(1)
+	}
+	dist[src] = 0
+	for range g.Len() {
+		for u := range g.Len() {
+			for _, e := range g.Edges(u) {
+				dist[e.To] = min(dist[e.To], dist[u]+e.Weight)
+			}
+		}
+	}
+	return dist
+}
+
+// kruskal is the reference for Prim: the total weight of a minimum
+// spanning forest.
+func kruskal(g *Graph) float64 {
+	var edges []Edge
+	for u := range g.Len() {
+		edges = append(edges, g.Edges(u)...)
+	}
+	slices.SortFunc(edges, func(a, b Edge) int { return int(a.Weight - b.Weight) })
+	parent := make([]int, g.Len())
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	total := 0.0
+	for _, e := range edges {
+		if a, b := find(e.From), find(e.To); a != b {
+			parent[a] = b
+			total += e.Weight
+		}
+	}
+	return total
+}
+
+func TestDijkstraRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		n := 1 + r.IntN(30)
+		g := randomGraph(r, n, r.IntN(n*4), false)
+		src := r.IntN(n)
+		dist, prev := Dijkstra(g, src)
+		want := bellmanFord(g, src)
+		if !slices.Equal(dist, want) {
+			t.Fatalf("dist = %v, want %v", dist, want)
+		}
+		for v := range n {
+			if math.IsInf(dist[v], 1) {
+				if prev[v] != -1 {
+					t.Fatalf("unreachable %d has prev %d", v, prev[v])
+				}
+				continue
+			}
+			// The path must start at src and its edges must add up to dist.
+			path := Path(prev, v)
+			if path[0] != src {
+				t.Fatalf("path to %d = %v, does not start at %d", v, path, src)
+			}
+			sum := 0.0
+			for i := 1; i < len(path); i++ {
+				w := math.Inf(1)
+				for _, e := range g.Edges(path[i-1]) {
+					if e.To == path[i] {
+						w = min(w, e.Weight)
+					}
+				}
+				sum += w
+			}
+			if sum != dist[v] {
+				t.Fatalf("path to %d = %v has weight %v, want %v", v, path, sum, dist[v])
+			}
+		}
+	}
+}
+
+func TestPrimRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 200 {
+		n := 1 + r.IntN(30)
+		g := randomGraph(r, n, r.IntN(n*3), true)
+		tree, total := Prim(g)
+		if want := kruskal(g); total != want {
+			t.Fatalf("total = %v, want %v", total, want)
+		}
+		sum := 0.0
+		for _, e := range tree {
+			sum += e.Weight
+		}
+		if sum != total {
+			t.Fatalf("tree weighs %v, total = %v", sum, total)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"math"
+
+	"github.com/ops2go/go-fundamentals/structures/dheap"
+)
+
+// Prim computes a minimum spanning forest of the undirected graph g, whose
+// edges should have been added with AddUndirected. It returns the edges of
+// the forest and their total weight. Each disconnected component gets its
+// own tree.
+func Prim(g *Graph) (tree []Edge, total float64) {
+	n := g.Len()
+	inTree := make([]bool, n)
+	// best[v] is the lightest known edge connecting v to the tree.
+	best := make([]Edge, n)
+	for v := range best {
+		best[v] = Edge{From: -1, To: v, Weight: math.Inf(1)}
+	}
+
+	h := dheap.New[int](heapArity, func(a, b float64) bool { return a < b })
+	for root := 0; root < n; root++ {
+		if inTree[root] {
+			continue
+		}
+		h.Push(root, 0)
+		for h.Len() > 0 {
+			u, _, _ := h.Pop()
+			inTree[u] = true
+			if best[u].From != -1 {
+				tree = append(tree, best[u])
+				total += best[u].Weight
+			}
+			for _, e := range g.Edges(u) {
+				if inTree[e.To] || e.Weight >= best[e.To].Weight {
+					continue
+				}
+				best[e.To] = e
+				if !h.DecreaseKey(e.To, e.Weight) {
+					h.Push(e.To, e.Weight)
+				}
+			}
+		}
+	}
+	return tree, total
+}
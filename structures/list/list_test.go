@@ -0,0 +1,193 @@
+package list
+
+import (
+	"container/list"
+	"slices"
+	"testing"
+)
+
+// checkList verifies l's links, length and element ownership against the
+// reference container/list.
+func checkList(t *testing.T, l *List[int], ref *list.List) {
+	t.Helper()
+	if l.Len() != ref.Len() {
+		t.Fatalf("Len = %d, want %d", l.Len(), ref.Len())
+	}
+	var want []int
+	for e := ref.Front(); e != nil; e = e.Next() {
+		want = append(want, e.Value.(int))
+	}
+	got := slices.Collect(l.All())
+	if !slices.Equal(got, want) {
+		t.Fatalf("All = %v, want %v", got, want)
+	}
+	back := slices.Collect(l.Backward())
+	slices.Reverse(back)
+	if !slices.Equal(back, want) {
+		t.Fatalf("Backward = %v, want reverse of %v", back, want)
+	}
+	var prev *Element[int]
+	for e := l.Front(); e != nil; e = e.Next() {
+		if e.list != l || e.Prev() != prev {
+			t.Fatalf("element %v has broken links", e.Value)
+		}
+		prev = e
+	}
+	if l.Back() != prev {
+		t.Fatal("Back is not the last element")
+	}
+}
+
+// FuzzList runs a program of list operations, encoded as bytes, against
+// List and container/list and checks that both end up the same.
+func FuzzList(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte{1, 1, 1, 3, 0, 4, 2, 5, 6, 6, 7, 1})
+	f.Add([]byte{0, 0, 0, 10, 0, 11, 12, 3, 13, 8})
+	f.Fuzz(func(t *testing.T, prog []byte) {
+		l, ref := New[int](), list.New()
+		var els []*Element[int]
+		var refs []*list.Element
+		pick := func(i int) (*Element[int], *list.Element) {
+			if len(els) == 0 {
+				return nil, nil
+			}
+			i %= len(els)
+			return els[i], refs[i]
+		}
+		drop := func(e *Element[int]) {
+			i := slices.Index(els, e)
+			els = slices.Delete(els, i, i+1)
+			refs = slices.Delete(refs, i, i+1)
+		}
+		for i, op := range prog {
+			v := i
+			arg := 0
+			if i+1 < len(prog) {
+				arg = int(prog[i+1])
+			}
+			e, r := pick(arg)
+			switch op % 14 {
+			case 0:
+				els, refs = append(els, l.PushFront(v)), append(refs, ref.PushFront(v))
+			case 1:
+				els, refs = append(els, l.PushBack(v)), append(refs, ref.PushBack(v))
+			case 2:
+				if e != nil {
+					els, refs = append(els, l.InsertBefore(v, e)), append(refs, ref.InsertBefore(v, r))
+				}
+			case 3:
+				if e != nil {
+					els, refs = append(els, l.InsertAfter(v, e)), append(refs, ref.InsertAfter(v, r))
+				}
+			case 4:
+				if e != nil {
+					if got, want := l.Remove(e), ref.Remove(r).(int); got != want {
+						t.Fatalf("Remove = %d, want %d", got, want)
+					}
+					drop(e)
+				}
+			case 5:
+				if e != nil {
+					l.MoveToFront(e)
+					ref.MoveToFront(r)
+				}
+			case 6:
+				if e != nil {
+					l.MoveToBack(e)
+					ref.MoveToBack(r)
+				}
+			case 7, 8:
+				m, mr := pick(arg / 3)
+				if e != nil {
+					if op%14 == 7 {
+						l.MoveBefore(e, m)
+						ref.MoveBefore(r, mr)
+					} else {
+						l.MoveAfter(e, m)
+						ref.MoveAfter(r, mr)
+					}
+				}
+			case 9:
+				// PushBackList copies, so the copies have no handles.
+				other, otherRef := New[int](), list.New()
+				other.PushBack(-v)
+				otherRef.PushBack(-v)
+				l.PushBackList(other)
+				ref.PushBackList(otherRef)
+			case 10:
+				other, otherRef := New[int](), list.New()
+				other.PushBack(-v)
+				otherRef.PushBack(-v)
+				l.PushFrontList(other)
+				ref.PushFrontList(otherRef)
+			case 11, 12, 13:
+				// Splices move elements, so they keep their handles; the
+				// reference copies them and is rebuilt to match.
+				other := New[int]()
+				moved := []*Element[int]{other.PushBack(-v), other.PushBack(-v - 1000)}
+				switch {
+				case op%14 == 11:
+					l.SpliceBack(other)
+				case op%14 == 12:
+					l.SpliceFront(other)
+				case e != nil:
+					l.SpliceAfter(e, other)
+				default:
+					continue
+				}
+				if other.Len() != 0 {
+					t.Fatal("splice left elements in other")
+				}
+				ref.Init()
+				els, refs = els[:0], refs[:0]
+				for x := l.Front(); x != nil; x = x.Next() {
+					els, refs = append(els, x), append(refs, ref.PushBack(x.Value))
+				}
+				for _, m := range moved {
+					if m.list != l {
+						t.Fatal("spliced element not re-parented")
+					}
+				}
+			}
+			checkList(t, l, ref)
+		}
+	})
+}
+
+func TestMerge(t *testing.T) {
+	less := func(a, b int) bool { return a/10 < b/10 }
+	tests := []struct {
+		a, b, want []int
+	}{
+		{nil, nil, nil},
+		{[]int{1, 3}, nil, []int{1, 3}},
+		{nil, []int{2}, []int{2}},
+		{[]int{10, 30, 50}, []int{20, 40, 60}, []int{10, 20, 30, 40, 50, 60}},
+		// Stable: equal keys from l come first.
+		{[]int{11, 12}, []int{13, 14}, []int{11, 12, 13, 14}},
+		{[]int{20, 21}, []int{10, 22, 30}, []int{10, 20, 21, 22, 30}},
+	}
+	for _, tt := range tests {
+		var l, other List[int]
+		for _, v := range tt.a {
+			l.PushBack(v)
+		}
+		var handles []*Element[int]
+		for _, v := range tt.b {
+			handles = append(handles, other.PushBack(v))
+		}
+		l.Merge(&other, less)
+		if got := slices.Collect(l.All()); !slices.Equal(got, tt.want) || l.Len() != len(tt.want) {
+			t.Errorf("Merge(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if other.Len() != 0 {
+			t.Errorf("Merge left %d elements in other", other.Len())
+		}
+		for _, h := range handles {
+			if h.list != &l {
+				t.Errorf("merged element %d not re-parented", h.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package list
+
+// SpliceFront moves all elements of other to the front of list l, keeping
+// their order, and leaves other empty. Existing *Element pointers remain
+// valid and now belong to l. It is a no-op if other is l.
+// The complexity is O(other.Len()), as every moved element is re-parented.
+func (l *List[T]) SpliceFront(other *List[T]) {
+	l.lazyInit()
+	l.splice(other, &l.root)
+}
+
+// SpliceBack moves all elements of other to the back of list l, as for
+// SpliceFront.
+func (l *List[T]) SpliceBack(other *List[T]) {
+	l.lazyInit()
+	l.splice(other, l.root.prev)
+}
+
+// SpliceBefore moves all elements of other immediately before mark, as for
+// SpliceFront. If mark is not an element of l, neither list is modified.
+// The mark must not be nil.
+func (l *List[T]) SpliceBefore(mark *Element[T], other *List[T]) {
+	if mark.list != l {
+		return
+	}
+	l.splice(other, mark.prev)
+}
+
+// SpliceAfter moves all elements of other immediately after mark, as for
+// SpliceFront. If mark is not an element of l, neither list is modified.
+// The mark must not be nil.
+func (l *List[T]) SpliceAfter(mark *Element[T], other *List[T]) {
+	if mark.list != l {
+		return
+	}
+	l.splice(other, mark)
+}
+
+// splice links the elements of other in after at and empties other.
+func (l *List[T]) splice(other *List[T], at *Element[T]) {
+	if other == l || other.Len() == 0 {
+		return
+	}
+	for e := other.root.next; e != &other.root; e = e.next {
+		e.list = l
+	}
+	first, last := other.root.next, other.root.prev
+	first.prev = at
+	last.next = at.next
+	at.next.prev = last
+	at.next = first
+	l.len += other.len
+	other.Init()
+}
+
+// Merge moves all elements of other into list l, leaving other empty.
+// Both lists must already be sorted according to less; the result is then
+// sorted too. The merge is stable: equal values from l come before those
+// from other. Existing *Element pointers remain valid.
+// It is a no-op if other is l.
+func (l *List[T]) Merge(other *List[T], less func(a, b T) bool) {
+	if other == l {
+		return
+	}
+	l.lazyInit()
+	e := l.Front()
+	for o := other.Front(); o != nil; {
+		// Advance through l until o belongs before e.
+		for e != nil && !less(o.Value, e.Value) {
+			e = e.Next()
+		}
+		next := o.Next()
+		other.remove(o)
+		if e == nil {
+			l.insert(o, l.root.prev)
+		} else {
+			l.insert(o, e.prev)
+		}
+		o = next
+	}
+}
@@ -0,0 +1,164 @@
+package pq
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrClosed is returned by Blocking operations after Close has been called.
+	ErrClosed = errors.New("pq: queue closed")
+	// ErrFull is returned by TryPush when a bounded queue is at capacity.
+	ErrFull = errors.New("pq: queue full")
+)
+
+// Blocking is a priority queue that is safe for concurrent use by
+// producers and consumers on separate goroutines.
+//
+// Pop blocks until a value is available, the context is cancelled, or the
+// queue is closed. If the queue was created with a positive capacity, Push
+// blocks while the queue is full and TryPush fails with ErrFull instead.
+type Blocking[T any] struct {
+	mu       sync.Mutex
+	q        *Queue[T]
+	capacity int
+	closed   bool
+	// changed is closed, and replaced, whenever values are pushed or popped
+	// or the queue is closed, waking every goroutine waiting on it.
+	changed chan struct{}
+}
+
+// NewBlocking returns an empty blocking queue ordered by less, as for New.
+// A capacity of zero or less means the queue is unbounded.
+func NewBlocking[T any](less func(a, b T) bool, capacity int) *Blocking[T] {
+	return &Blocking[T]{
+		q:        New(less),
+		capacity: capacity,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Len returns the number of values in the queue.
+func (b *Blocking[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.q.Len()
+}
+
+// Push adds v to the queue, waiting for room if the queue is bounded and
+// full. It returns ErrClosed if the queue is closed, or the context's error
+// if ctx is done before room is available.
+func (b *Blocking[T]) Push(ctx context.Context, v T) error {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return ErrClosed
+		}
+		if !b.full() {
+			b.push(v)
+			b.mu.Unlock()
+			return nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TryPush adds v to the queue without waiting. It returns ErrFull if the
+// queue is bounded and full, and ErrClosed if the queue is closed.
+func (b *Blocking[T]) TryPush(v T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	if b.full() {
+		return ErrFull
+	}
+	b.push(v)
+	return nil
+}
+
+// Pop removes and returns the value at the head of the queue, waiting until
+// one is available. Values still queued when the queue is closed are
+// handed out before Pop starts returning ErrClosed. If ctx is done first,
+// Pop returns the context's error.
+func (b *Blocking[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		b.mu.Lock()
+		if v, ok := b.pop(); ok {
+			b.mu.Unlock()
+			return v, nil
+		}
+		if b.closed {
+			b.mu.Unlock()
+			var zero T
+			return zero, ErrClosed
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// TryPop removes and returns the value at the head of the queue without
+// waiting. The boolean is false if the queue is empty.
+func (b *Blocking[T]) TryPop() (T, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pop()
+}
+
+// Close closes the queue and wakes every goroutine blocked in Push or Pop.
+// Subsequent pushes fail with ErrClosed; pops drain the remaining values
+// and then fail with ErrClosed. Calling Close more than once is a no-op.
+func (b *Blocking[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	b.broadcast()
+}
+
+// full reports whether a bounded queue is at capacity. b.mu must be held.
+func (b *Blocking[T]) full() bool {
+	return b.capacity > 0 && b.q.Len() >= b.capacity
+}
+
+// push adds v and wakes waiters. b.mu must be held.
+func (b *Blocking[T]) push(v T) {
+	b.q.Push(v)
+	b.broadcast()
+}
+
+// pop removes the head value, if any, and wakes waiters. b.mu must be held.
+func (b *Blocking[T]) pop() (T, bool) {
+	v, ok := b.q.Pop()
+	if ok {
+		b.broadcast()
+	}
+	return v, ok
+}
+
+// broadcast wakes every goroutine waiting on the current changed channel.
+// b.mu must be held.
+func (b *Blocking[T]) broadcast() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
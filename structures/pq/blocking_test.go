@@ -0,0 +1,208 @@
+package pq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func less(a, b int) bool { return a < b }
+
+// returnsWithin runs f on a goroutine and reports whether it returns
+// within d.
+func returnsWithin(d time.Duration, f func()) (done <-chan struct{}, ok bool) {
+	ch := make(chan struct{})
+	go func() {
+		f()
+		close(ch)
+	}()
+	select {
+	case <-ch:
+		return ch, true
+	case <-time.After(d):
+		return ch, false
+	}
+}
+
+func TestBlockingPopWaitsForPush(t *testing.T) {
+	b := NewBlocking(less, 0)
+	var got int
+	var err error
+	done, ok := returnsWithin(20*time.Millisecond, func() { got, err = b.Pop(context.Background()) })
+	if ok {
+		t.Fatal("Pop returned from an empty queue")
+	}
+	if err := b.TryPush(7); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pop did not return after Push")
+	}
+	if err != nil || got != 7 {
+		t.Fatalf("Pop = %d, %v, want 7, nil", got, err)
+	}
+}
+
+func TestBlockingPushWaitsAtCapacity(t *testing.T) {
+	b := NewBlocking(less, 2)
+	ctx := context.Background()
+	for _, v := range []int{3, 1} {
+		if err := b.Push(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var err error
+	done, ok := returnsWithin(20*time.Millisecond, func() { err = b.Push(ctx, 2) })
+	if ok {
+		t.Fatal("Push returned on a full queue")
+	}
+	if v, ok := b.TryPop(); !ok || v != 1 {
+		t.Fatalf("TryPop = %d, %v, want 1, true", v, ok)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Push did not return after Pop made room")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := b.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+}
+
+func TestBlockingTry(t *testing.T) {
+	b := NewBlocking(less, 1)
+	if v, ok := b.TryPop(); ok {
+		t.Fatalf("TryPop on an empty queue = %d, true", v)
+	}
+	if err := b.TryPush(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.TryPush(2); err != ErrFull {
+		t.Fatalf("TryPush on a full queue = %v, want %v", err, ErrFull)
+	}
+	if v, ok := b.TryPop(); !ok || v != 1 {
+		t.Fatalf("TryPop = %d, %v, want 1, true", v, ok)
+	}
+
+	// An unbounded queue is never full.
+	u := NewBlocking(less, 0)
+	for i := range 1000 {
+		if err := u.TryPush(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBlockingContext(t *testing.T) {
+	b := NewBlocking(less, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.Pop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Pop = %v, want %v", err, context.DeadlineExceeded)
+	}
+	b.TryPush(1)
+	if err := b.Push(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("Push = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBlockingClose(t *testing.T) {
+	ctx := context.Background()
+	full := NewBlocking(less, 1)
+	full.TryPush(5)
+	empty := NewBlocking(less, 1)
+
+	var pushErr, popErr error
+	pushDone, _ := returnsWithin(0, func() { pushErr = full.Push(ctx, 6) })
+	popDone, _ := returnsWithin(0, func() { _, popErr = empty.Pop(ctx) })
+	time.Sleep(20 * time.Millisecond)
+	full.Close()
+	empty.Close()
+	empty.Close() // a no-op
+	for _, done := range []<-chan struct{}{pushDone, popDone} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close did not release a blocked caller")
+		}
+	}
+	if pushErr != ErrClosed || popErr != ErrClosed {
+		t.Fatalf("blocked Push = %v, Pop = %v, want %v", pushErr, popErr, ErrClosed)
+	}
+
+	// Queued values are still handed out, then Pop fails.
+	if err := full.TryPush(7); err != ErrClosed {
+		t.Fatalf("TryPush after Close = %v, want %v", err, ErrClosed)
+	}
+	if v, err := full.Pop(ctx); err != nil || v != 5 {
+		t.Fatalf("Pop after Close = %d, %v, want 5, nil", v, err)
+	}
+	if _, err := full.Pop(ctx); err != ErrClosed {
+		t.Fatalf("Pop of a drained closed queue = %v, want %v", err, ErrClosed)
+	}
+}
+
+// TestBlockingStress is meant to be run with -race.
+func TestBlockingStress(t *testing.T) {
+	const producers, consumers, perProducer = 4, 4, 500
+	b := NewBlocking(less, 8)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				if err := b.Push(ctx, p*perProducer+i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	var mu sync.Mutex
+	var got []int
+	var cwg sync.WaitGroup
+	for range consumers {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			for {
+				v, err := b.Pop(ctx)
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				got = append(got, v)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	b.Close()
+	cwg.Wait()
+
+	slices.Sort(got)
+	if len(got) != producers*perProducer {
+		t.Fatalf("popped %d values, want %d", len(got), producers*perProducer)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("value %d missing or duplicated", i)
+		}
+	}
+}
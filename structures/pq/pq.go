@@ -0,0 +1,124 @@
+// Package pq implements a generic priority queue built on container/heap.
+//
+// It is the importable form of the PriorityQueue example in
+// structures/queue.go: values of any type are ordered by a caller-supplied
+// less function, and every pushed value is returned as an *Item handle that
+// can later be used to update or remove it in O(log n).
+package pq
+
+import "container/heap"
+
+// An Item is a handle to a value held in a Queue.
+type Item[T any] struct {
+	value T
+	// The index is needed by Update and Remove and is maintained by the heap.Interface methods.
+	index int // The index of the item in the heap, or -1 once it has left the queue.
+	owner *items[T]
+}
+
+// Value returns the value held by the item.
+func (it *Item[T]) Value() T { return it.value }
+
+// A Queue is a priority queue of values of type T. The value for which less
+// reports true against every other value is at the head of the queue.
+//
+// The zero Queue is not usable; create queues with New.
+// A Queue is not safe for concurrent use; see Blocking for that.
+type Queue[T any] struct {
+	h items[T]
+}
+
+// New returns an empty queue ordered by less. less(a, b) reports whether a
+// must leave the queue before b; use a greater-than comparison for a
+// max-queue, as structures/queue.go does.
+func New[T any](less func(a, b T) bool) *Queue[T] {
+	return &Queue[T]{h: items[T]{less: less}}
+}
+
+// Len returns the number of values in the queue.
+func (q *Queue[T]) Len() int { return len(q.h.s) }
+
+// Push adds v to the queue and returns its handle.
+func (q *Queue[T]) Push(v T) *Item[T] {
+	it := &Item[T]{value: v, owner: &q.h}
+	heap.Push(&q.h, it)
+	return it
+}
+
+// Pop removes and returns the value at the head of the queue.
+// The boolean is false if the queue is empty.
+func (q *Queue[T]) Pop() (T, bool) {
+	if len(q.h.s) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(&q.h).(*Item[T]).value, true
+}
+
+// Peek returns the value at the head of the queue without removing it.
+// The boolean is false if the queue is empty.
+func (q *Queue[T]) Peek() (T, bool) {
+	if len(q.h.s) == 0 {
+		var zero T
+		return zero, false
+	}
+	return q.h.s[0].value, true
+}
+
+// Update replaces the value held by it and restores the heap ordering.
+// It reports false, and changes nothing, if it is not currently in q.
+func (q *Queue[T]) Update(it *Item[T], v T) bool {
+	if !q.contains(it) {
+		return false
+	}
+	it.value = v
+	heap.Fix(&q.h, it.index)
+	return true
+}
+
+// Remove removes it from the queue and returns its value.
+// It reports false if it is not currently in q.
+func (q *Queue[T]) Remove(it *Item[T]) (T, bool) {
+	if !q.contains(it) {
+		var zero T
+		return zero, false
+	}
+	return heap.Remove(&q.h, it.index).(*Item[T]).value, true
+}
+
+// contains reports whether it is a live handle belonging to q.
+func (q *Queue[T]) contains(it *Item[T]) bool {
+	return it != nil && it.owner == &q.h && it.index >= 0 && it.index < len(q.h.s) && q.h.s[it.index] == it
+}
+
+// items implements heap.Interface and holds Items.
+type items[T any] struct {
+	s    []*Item[T]
+	less func(a, b T) bool
+}
+
+func (h items[T]) Len() int { return len(h.s) }
+
+func (h items[T]) Less(i, j int) bool { return h.less(h.s[i].value, h.s[j].value) }
+
+func (h items[T]) Swap(i, j int) {
+	h.s[i], h.s[j] = h.s[j], h.s[i]
+	h.s[i].index = i
+	h.s[j].index = j
+}
+
+func (h *items[T]) Push(x any) {
+	it := x.(*Item[T])
+	it.index = len(h.s)
+	h.s = append(h.s, it)
+}
+
+func (h *items[T]) Pop() any {
+	old := h.s
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	it.index = -1  // for safety
+	h.s = old[0 : n-1]
+	return it
+}
@@ -0,0 +1,278 @@
+package pq
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+type prio struct {
+	value    string
+	priority int
+}
+
+func byPriority(a, b prio) bool { return a.priority > b.priority }
+
+func drain[T any](q *Queue[T]) []T {
+	var out []T
+	for {
+		v, ok := q.Pop()
+		if !ok {
+			return out
+		}
+		out = append(out, v)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		push []int
+		want []int
+	}{
+		{"empty", nil, nil},
+		{"one", []int{7}, []int{7}},
+		{"sorted", []int{1, 2, 3, 4}, []int{1, 2, 3, 4}},
+		{"reversed", []int{4, 3, 2, 1}, []int{1, 2, 3, 4}},
+		{"duplicates", []int{2, 1, 2, 1, 3}, []int{1, 1, 2, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := New(func(a, b int) bool { return a < b })
+			for _, v := range tt.push {
+				q.Push(v)
+			}
+			if q.Len() != len(tt.push) {
+				t.Fatalf("Len = %d, want %d", q.Len(), len(tt.push))
+			}
+			if len(tt.want) > 0 {
+				if v, ok := q.Peek(); !ok || v != tt.want[0] {
+					t.Fatalf("Peek = %v, %v, want %v, true", v, ok, tt.want[0])
+				}
+			}
+			if got := drain(q); !slices.Equal(got, tt.want) {
+				t.Fatalf("popped %v, want %v", got, tt.want)
+			}
+			if _, ok := q.Peek(); ok {
+				t.Fatal("Peek on empty queue reported ok")
+			}
+		})
+	}
+}
+
+func TestQueueUpdateRemove(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(q *Queue[prio], items map[string]*Item[prio]) bool
+		want []string
+	}{
+		{
+			name: "update raises",
+			op:   func(q *Queue[prio], it map[string]*Item[prio]) bool { return q.Update(it["orange"], prio{"orange", 5}) },
+			want: []string{"orange", "pear", "banana", "apple"},
+		},
+		{
+			name: "update lowers",
+			op:   func(q *Queue[prio], it map[string]*Item[prio]) bool { return q.Update(it["pear"], prio{"pear", 0}) },
+			want: []string{"banana", "apple", "orange", "pear"},
+		},
+		{
+			name: "remove head",
+			op: func(q *Queue[prio], it map[string]*Item[prio]) bool {
+				v, ok := q.Remove(it["pear"])
+				return ok && v.value == "pear"
+			},
+			want: []string{"banana", "apple", "orange"},
+		},
+		{
+			name: "remove middle",
+			op: func(q *Queue[prio], it map[string]*Item[prio]) bool {
+				v, ok := q.Remove(it["apple"])
+				return ok && v.value == "apple"
+			},
+			want: []string{"pear", "banana", "orange"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := New(byPriority)
+			items := make(map[string]*Item[prio])
+			for _, p := range []prio{{"banana", 3}, {"apple", 2}, {"pear", 4}, {"orange", 1}} {
+				items[p.value] = q.Push(p)
+			}
+			if !tt.op(q, items) {
+				t.Fatal("operation reported false")
+			}
+			var got []string
+			for _, p := range drain(q) {
+				got = append(got, p.value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("popped %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueStaleHandles(t *testing.T) {
+	q := New(byPriority)
+	other := New(byPriority)
+	a := q.Push(prio{"a", 1})
+	b := other.Push(prio{"b", 2})
+
+	if q.Update(b, prio{"b", 9}) {
+		t.Error("Update accepted a handle from another queue")
+	}
+	if _, ok := q.Remove(b); ok {
+		t.Error("Remove accepted a handle from another queue")
+	}
+	if q.Update(nil, prio{}) {
+		t.Error("Update accepted a nil handle")
+	}
+
+	q.Pop()
+	if q.Update(a, prio{"a", 9}) {
+		t.Error("Update accepted a popped handle")
+	}
+	if _, ok := q.Remove(a); ok {
+		t.Error("Remove accepted a popped handle")
+	}
+	if a.Value().value != "a" {
+		t.Errorf("Value = %v after a failed Update", a.Value())
+	}
+}
+
+func TestQueueRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	q := New(func(a, b int) bool { return a < b })
+	var live []*Item[int]
+	var want []int
+	for range 2000 {
+		switch r.IntN(4) {
+		case 0, 1:
+			v := r.IntN(100)
+			live = append(live, q.Push(v))
+		case 2:
+			if len(live) > 0 {
+				i := r.IntN(len(live))
+				q.Update(live[i], r.IntN(100))
+			}
+		case 3:
+			if len(live) > 0 {
+				i := r.IntN(len(live))
+				q.Remove(live[i])
+				live = slices.Delete(live, i, i+1)
+			}
+		}
+	}
+	for _, it := range live {
+		want = append(want, it.Value())
+	}
+	slices.Sort(want)
+	if got := drain(q); !slices.Equal(got, want) {
+		t.Fatalf("popped %v, want %v", got, want)
+	}
+}
+
+// heapItem and heapQueue are the heap.Interface priority queue from
+// structures/queue.go, which the benchmarks compare against.
+type heapItem struct {
+	value    string
+	priority int
+	index    int
+}
+
+type heapQueue []*heapItem
+
+func (pq heapQueue) Len() int           { return len(pq) }
+func (pq heapQueue) Less(i, j int) bool { return pq[i].priority > pq[j].priority }
+
+func (pq heapQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].index = i
+	pq[j].index = j
+}
+
+func (pq *heapQueue) Push(x any) {
+	item := x.(*heapItem)
+	item.index = len(*pq)
+	*pq = append(*pq, item)
+}
+
+func (pq *heapQueue) Pop() any {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	item.index = -1
+	*pq = old[0 : n-1]
+	return item
+}
+
+const benchSize = 1000
+
+func benchPriorities() []int {
+	r := rand.New(rand.NewPCG(1, 2))
+	ps := make([]int, benchSize)
+	for i := range ps {
+		ps[i] = r.IntN(benchSize)
+	}
+	return ps
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	ps := benchPriorities()
+	b.Run("pq", func(b *testing.B) {
+		for b.Loop() {
+			q := New(byPriority)
+			for _, p := range ps {
+				q.Push(prio{"x", p})
+			}
+			for q.Len() > 0 {
+				q.Pop()
+			}
+		}
+	})
+	b.Run("heap", func(b *testing.B) {
+		for b.Loop() {
+			var q heapQueue
+			for _, p := range ps {
+				heap.Push(&q, &heapItem{value: "x", priority: p})
+			}
+			for q.Len() > 0 {
+				heap.Pop(&q)
+			}
+		}
+	})
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	ps := benchPriorities()
+	b.Run("pq", func(b *testing.B) {
+		q := New(byPriority)
+		items := make([]*Item[prio], len(ps))
+		for i, p := range ps {
+			items[i] = q.Push(prio{"x", p})
+		}
+		i := 0
+		for b.Loop() {
+			q.Update(items[i%len(items)], prio{"x", ps[(i*7)%len(ps)]})
+			i++
+		}
+	})
+	b.Run("heap", func(b *testing.B) {
+		var q heapQueue
+		items := make([]*heapItem, len(ps))
+		for i, p := range ps {
+			items[i] = &heapItem{value: "x", priority: p}
+			heap.Push(&q, items[i])
+		}
+		i := 0
+		for b.Loop() {
+			it := items[i%len(items)]
+			it.priority = ps[(i*7)%len(ps)]
+			heap.Fix(&q, it.index)
+			i++
+		}
+	})
+}
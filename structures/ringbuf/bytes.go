@@ -0,0 +1,97 @@
+package ringbuf
+
+import "io"
+
+// Bytes is a ring of bytes that implements io.Reader and io.Writer.
+// It is safe for concurrent use.
+//
+// In Overwrite mode a Bytes keeps the last Cap() bytes written, which makes
+// it suitable for holding the tail of a log.
+type Bytes struct {
+	Ring[byte]
+}
+
+// NewBytes returns an empty byte ring holding at most capacity bytes.
+// It panics if capacity is not positive.
+func NewBytes(capacity int, mode Mode) *Bytes {
+	b := &Bytes{}
+	b.init(capacity, mode)
+	return b
+}
+
+// Write appends p to the ring. In Overwrite mode it always writes all of p,
+// discarding the oldest bytes as needed. In Block mode it waits for room
+// until all of p is written or the ring is closed. In Error mode it writes
+// as much of p as fits and returns ErrFull if that is not all of it.
+func (b *Bytes) Write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.mode == Overwrite && len(p) > len(b.buf) {
+		// Only the last len(b.buf) bytes can survive.
+		n = len(p) - len(b.buf)
+		p = p[n:]
+	}
+	for len(p) > 0 {
+		if err := b.waitRoom(); err != nil {
+			return n, err
+		}
+		if b.n == len(b.buf) {
+			// Overwrite: drop the oldest bytes to make room for p.
+			drop := min(len(p), b.n)
+			b.head = b.index(drop)
+			b.n -= drop
+		}
+		m := b.write(p)
+		n += m
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// WriteByte appends c to the ring as for Push.
+func (b *Bytes) WriteByte(c byte) error { return b.Push(c) }
+
+// Read reads up to len(p) of the oldest bytes from the ring. It returns
+// io.EOF when the ring is empty; Read never blocks.
+func (b *Bytes) Read(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.n == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n = min(len(p), b.n)
+	b.copyOut(p[:n])
+	b.head = b.index(n)
+	b.n -= n
+	b.notFull.Broadcast()
+	return n, nil
+}
+
+// ReadByte removes and returns the oldest byte, or io.EOF if the ring is empty.
+func (b *Bytes) ReadByte() (byte, error) {
+	c, ok := b.Pop()
+	if !ok {
+		return 0, io.EOF
+	}
+	return c, nil
+}
+
+// String returns the bytes in the ring, oldest first, without removing them.
+func (b *Bytes) String() string { return string(b.Snapshot()) }
+
+// write copies as much of p as fits after the newest byte and returns how
+// many bytes it copied. b.mu must be held.
+func (b *Bytes) write(p []byte) int {
+	tail := b.index(b.n)
+	free := len(b.buf) - b.n
+	end := min(tail+free, len(b.buf))
+	m := copy(b.buf[tail:end], p)
+	if m < len(p) && m < free {
+		m += copy(b.buf[:free-m], p[m:])
+	}
+	b.n += m
+	return m
+}
@@ -0,0 +1,161 @@
+package ringbuf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestBytesOverwrite(t *testing.T) {
+	b := NewBytes(8, Overwrite)
+	fmt.Fprintf(b, "line one\n")
+	fmt.Fprintf(b, "two\n")
+	if got := b.String(); got != "one\ntwo\n" {
+		t.Fatalf("String = %q, want %q", got, "one\ntwo\n")
+	}
+	// A write longer than the ring keeps its tail.
+	n, err := b.Write([]byte("0123456789abc"))
+	if n != 13 || err != nil {
+		t.Fatalf("Write = %d, %v, want 13, nil", n, err)
+	}
+	if got := b.String(); got != "56789abc" {
+		t.Fatalf("String = %q, want %q", got, "56789abc")
+	}
+}
+
+func TestBytesError(t *testing.T) {
+	b := NewBytes(4, Error)
+	n, err := b.Write([]byte("abcdef"))
+	if n != 4 || err != ErrFull {
+		t.Fatalf("Write = %d, %v, want 4, %v", n, err, ErrFull)
+	}
+	if err := b.WriteByte('x'); err != ErrFull {
+		t.Fatalf("WriteByte = %v, want %v", err, ErrFull)
+	}
+	if got := b.String(); got != "abcd" {
+		t.Fatalf("String = %q, want %q", got, "abcd")
+	}
+}
+
+func TestBytesReader(t *testing.T) {
+	b := NewBytes(4, Error)
+	b.Write([]byte("ab"))
+	p := make([]byte, 1)
+	b.Read(p)
+	b.Write([]byte("cde")) // wraps
+	got, err := io.ReadAll(b)
+	if err != nil || string(got) != "bcde" {
+		t.Fatalf("ReadAll = %q, %v, want %q, nil", got, err, "bcde")
+	}
+	if n, err := b.Read(p); n != 0 || err != io.EOF {
+		t.Fatalf("Read of an empty ring = %d, %v, want 0, EOF", n, err)
+	}
+	if n, err := b.Read(nil); n != 0 || err != nil {
+		t.Fatalf("Read(nil) = %d, %v, want 0, nil", n, err)
+	}
+	if _, err := b.ReadByte(); err != io.EOF {
+		t.Fatalf("ReadByte of an empty ring = %v, want EOF", err)
+	}
+}
+
+func TestBytesBlockingWrite(t *testing.T) {
+	b := NewBytes(4, Block)
+	in := bytes.Repeat([]byte("0123456789"), 100)
+	done := make(chan error)
+	go func() {
+		_, err := b.Write(in)
+		done <- err
+	}()
+
+	var out []byte
+	p := make([]byte, 3)
+	deadline := time.After(5 * time.Second)
+	for len(out) < len(in) {
+		n, err := b.Read(p)
+		out = append(out, p[:n]...)
+		if err == io.EOF {
+			select {
+			case <-deadline:
+				t.Fatalf("read %d of %d bytes", len(out), len(in))
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, in) {
+		t.Fatal("bytes read differ from bytes written")
+	}
+}
+
+func TestBytesCloseWakesWriter(t *testing.T) {
+	b := NewBytes(2, Block)
+	done := make(chan error)
+	var n int
+	go func() {
+		var err error
+		n, err = b.Write([]byte("abcd"))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	b.Close()
+	select {
+	case err := <-done:
+		if err != ErrClosed || n != 2 {
+			t.Fatalf("Write = %d, %v, want 2, %v", n, err, ErrClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not wake a blocked Write")
+	}
+	if got := b.String(); got != "ab" {
+		t.Fatalf("String = %q, want %q", got, "ab")
+	}
+}
+
+// TestBytesRandom checks Write and Read against a slice in Overwrite and
+// Error modes.
+func TestBytesRandom(t *testing.T) {
+	for _, mode := range []Mode{Overwrite, Error} {
+		rnd := rand.New(rand.NewPCG(2, uint64(mode)))
+		const capacity = 7
+		b := NewBytes(capacity, mode)
+		var model []byte
+		next := byte(0)
+		for i := range 5000 {
+			if rnd.IntN(2) == 0 {
+				p := make([]byte, rnd.IntN(2*capacity))
+				for j := range p {
+					p[j] = next
+					next++
+				}
+				n, err := b.Write(p)
+				if mode == Overwrite {
+					model = append(model, p...)
+					model = model[max(0, len(model)-capacity):]
+				} else {
+					fit := min(len(p), capacity-len(model))
+					model = append(model, p[:fit]...)
+					if n != fit || (fit < len(p)) != (err == ErrFull) {
+						t.Fatalf("mode %d: Write(%d bytes) = %d, %v with %d free", mode, len(p), n, err, fit)
+					}
+				}
+			} else {
+				p := make([]byte, rnd.IntN(capacity+2))
+				n, _ := b.Read(p)
+				want := min(len(p), len(model))
+				if n != want || !bytes.Equal(p[:n], model[:n]) {
+					t.Fatalf("mode %d: Read = %v, model %v", mode, p[:n], model)
+				}
+				model = model[n:]
+			}
+			if got := b.Snapshot(); !bytes.Equal(got, model) {
+				t.Fatalf("mode %d: step %d: Snapshot = %v, model %v", mode, i, got, model)
+			}
+		}
+	}
+}
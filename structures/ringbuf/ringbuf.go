@@ -0,0 +1,177 @@
+// Package ringbuf implements a fixed-capacity, array-backed ring buffer.
+//
+// Unlike container/ring, as used in structures/doring.go and linkring.go,
+// the buffer holds values of a single type in one slice and decides what
+// happens when it is full: overwrite the oldest value, block until there is
+// room, or fail. The byte specialization, Bytes, implements io.Reader and
+// io.Writer so it can hold the tail of a log.
+package ringbuf
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrFull is returned when pushing to a full buffer in Error mode.
+	ErrFull = errors.New("ringbuf: buffer full")
+	// ErrClosed is returned when pushing to a closed buffer.
+	ErrClosed = errors.New("ringbuf: buffer closed")
+)
+
+// Mode decides what a push does when the buffer is full.
+type Mode int
+
+const (
+	Overwrite Mode = iota // discard the oldest value
+	Block                 // wait until a value is popped or the buffer is closed
+	Error                 // fail with ErrFull
+)
+
+// A Ring is a fixed-capacity FIFO buffer. It is safe for concurrent use.
+type Ring[T any] struct {
+	mu      sync.Mutex
+	notFull sync.Cond
+	buf     []T
+	head    int // index of the oldest value
+	n       int // number of values held
+	mode    Mode
+	closed  bool
+}
+
+// New returns an empty ring holding at most capacity values.
+// It panics if capacity is not positive.
+func New[T any](capacity int, mode Mode) *Ring[T] {
+	r := &Ring[T]{}
+	r.init(capacity, mode)
+	return r
+}
+
+func (r *Ring[T]) init(capacity int, mode Mode) {
+	if capacity <= 0 {
+		panic("ringbuf: capacity must be positive")
+	}
+	r.buf = make([]T, capacity)
+	r.mode = mode
+	r.notFull.L = &r.mu
+}
+
+// Len returns the number of values in the ring.
+func (r *Ring[T]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// Cap returns the capacity of the ring.
+func (r *Ring[T]) Cap() int { return len(r.buf) }
+
+// Push appends v at the newest end of the ring. What happens when the ring
+// is full depends on its Mode. Push returns ErrClosed once the ring is
+// closed.
+func (r *Ring[T]) Push(v T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.waitRoom(); err != nil {
+		return err
+	}
+	if r.n == len(r.buf) {
+		// Overwrite: the newest value takes the oldest value's slot.
+		r.buf[r.head] = v
+		r.head = r.index(1)
+		return nil
+	}
+	r.buf[r.index(r.n)] = v
+	r.n++
+	return nil
+}
+
+// Pop removes and returns the oldest value. The boolean is false if the
+// ring is empty; Pop never blocks.
+func (r *Ring[T]) Pop() (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var zero T
+	if r.n == 0 {
+		return zero, false
+	}
+	v := r.buf[r.head]
+	r.buf[r.head] = zero
+	r.head = r.index(1)
+	r.n--
+	r.notFull.Broadcast()
+	return v, true
+}
+
+// Peek returns the oldest value without removing it. The boolean is false
+// if the ring is empty.
+func (r *Ring[T]) Peek() (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n == 0 {
+		var zero T
+		return zero, false
+	}
+	return r.buf[r.head], true
+}
+
+// Snapshot returns a copy of the values in the ring, oldest first.
+func (r *Ring[T]) Snapshot() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := make([]T, r.n)
+	r.copyOut(s)
+	return s
+}
+
+// Reset empties the ring.
+func (r *Ring[T]) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.buf)
+	r.head, r.n = 0, 0
+	r.notFull.Broadcast()
+}
+
+// Close wakes every goroutine blocked in a push and makes later pushes
+// fail with ErrClosed. Values already in the ring can still be popped.
+func (r *Ring[T]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.notFull.Broadcast()
+	return nil
+}
+
+// waitRoom applies the ring's Mode when it is full. It returns with the ring
+// either having room or, in Overwrite mode, full. r.mu must be held.
+func (r *Ring[T]) waitRoom() error {
+	for {
+		if r.closed {
+			return ErrClosed
+		}
+		if r.n < len(r.buf) {
+			return nil
+		}
+		switch r.mode {
+		case Error:
+			return ErrFull
+		case Block:
+			r.notFull.Wait()
+		default:
+			return nil
+		}
+	}
+}
+
+// index returns the buffer index i positions after the oldest value.
+func (r *Ring[T]) index(i int) int {
+	return (r.head + i) % len(r.buf)
+}
+
+// copyOut copies the oldest len(dst) values into dst, which must not be
+// longer than r.n. r.mu must be held.
+func (r *Ring[T]) copyOut(dst []T) {
+	n := copy(dst, r.buf[r.head:min(r.head+len(dst), len(r.buf))])
+	copy(dst[n:], r.buf)
+}
@@ -0,0 +1,175 @@
+package ringbuf
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func pushAll(t *testing.T, r *Ring[int], vs ...int) {
+	t.Helper()
+	for _, v := range vs {
+		if err := r.Push(v); err != nil {
+			t.Fatalf("Push(%d): %v", v, err)
+		}
+	}
+}
+
+func TestRingOverwrite(t *testing.T) {
+	r := New[int](3, Overwrite)
+	pushAll(t, r, 1, 2, 3, 4, 5)
+	if got := r.Snapshot(); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Fatalf("Snapshot = %v, want [3 4 5]", got)
+	}
+	if v, ok := r.Peek(); !ok || v != 3 {
+		t.Fatalf("Peek = %d, %v, want 3, true", v, ok)
+	}
+	if r.Len() != 3 || r.Cap() != 3 {
+		t.Fatalf("Len, Cap = %d, %d, want 3, 3", r.Len(), r.Cap())
+	}
+}
+
+func TestRingError(t *testing.T) {
+	r := New[int](2, Error)
+	pushAll(t, r, 1, 2)
+	if err := r.Push(3); err != ErrFull {
+		t.Fatalf("Push on a full ring = %v, want %v", err, ErrFull)
+	}
+	if got := r.Snapshot(); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("Snapshot = %v, want [1 2]", got)
+	}
+}
+
+func TestRingWraparound(t *testing.T) {
+	r := New[int](3, Error)
+	pushAll(t, r, 1, 2)
+	r.Pop()
+	r.Pop()
+	// head is now at index 2, so these values wrap.
+	pushAll(t, r, 3, 4, 5)
+	if got := r.Snapshot(); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Fatalf("Snapshot = %v, want [3 4 5]", got)
+	}
+	for _, want := range []int{3, 4, 5} {
+		if v, ok := r.Pop(); !ok || v != want {
+			t.Fatalf("Pop = %d, %v, want %d, true", v, ok, want)
+		}
+	}
+	if _, ok := r.Pop(); ok {
+		t.Fatal("Pop on an empty ring reported ok")
+	}
+	if _, ok := r.Peek(); ok {
+		t.Fatal("Peek on an empty ring reported ok")
+	}
+}
+
+func TestRingSnapshotIsCopy(t *testing.T) {
+	r := New[int](2, Overwrite)
+	pushAll(t, r, 1, 2)
+	s := r.Snapshot()
+	s[0] = 99
+	if v, _ := r.Peek(); v != 1 {
+		t.Fatalf("Snapshot aliases the ring: Peek = %d", v)
+	}
+	r.Reset()
+	if r.Len() != 0 || len(r.Snapshot()) != 0 {
+		t.Fatal("Reset left values in the ring")
+	}
+}
+
+func TestRingBlock(t *testing.T) {
+	r := New[int](1, Block)
+	pushAll(t, r, 1)
+	done := make(chan error)
+	go func() { done <- r.Push(2) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Push on a full ring returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if v, ok := r.Pop(); !ok || v != 1 {
+		t.Fatalf("Pop = %d, %v, want 1, true", v, ok)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Push did not return after Pop made room")
+	}
+	if v, _ := r.Peek(); v != 2 {
+		t.Fatalf("Peek = %d, want 2", v)
+	}
+}
+
+func TestRingCloseWakesWriters(t *testing.T) {
+	r := New[int](1, Block)
+	pushAll(t, r, 1)
+	const writers = 3
+	done := make(chan error, writers)
+	for i := range writers {
+		go func() { done <- r.Push(10 + i) }()
+	}
+	time.Sleep(20 * time.Millisecond)
+	r.Close()
+	for range writers {
+		select {
+		case err := <-done:
+			if err != ErrClosed {
+				t.Fatalf("blocked Push = %v, want %v", err, ErrClosed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close did not wake a blocked Push")
+		}
+	}
+	// Readers never block, and what was queued can still be read.
+	if v, ok := r.Pop(); !ok || v != 1 {
+		t.Fatalf("Pop after Close = %d, %v, want 1, true", v, ok)
+	}
+	if _, ok := r.Pop(); ok {
+		t.Fatal("Pop of an empty closed ring reported ok")
+	}
+}
+
+// TestRingRandom checks a ring against a slice for each mode.
+func TestRingRandom(t *testing.T) {
+	for _, mode := range []Mode{Overwrite, Error} {
+		rnd := rand.New(rand.NewPCG(1, uint64(mode)))
+		const capacity = 5
+		r := New[int](capacity, mode)
+		var model []int
+		for i := range 5000 {
+			switch rnd.IntN(3) {
+			case 0, 1:
+				err := r.Push(i)
+				switch {
+				case len(model) < capacity:
+					model = append(model, i)
+				case mode == Overwrite:
+					model = append(model[1:], i)
+				default:
+					if err != ErrFull {
+						t.Fatalf("mode %d: Push on full = %v", mode, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("mode %d: Push = %v", mode, err)
+				}
+			case 2:
+				v, ok := r.Pop()
+				if ok != (len(model) > 0) || ok && v != model[0] {
+					t.Fatalf("mode %d: Pop = %d, %v, model %v", mode, v, ok, model)
+				}
+				if ok {
+					model = model[1:]
+				}
+			}
+			if got := r.Snapshot(); !slices.Equal(got, model) {
+				t.Fatalf("mode %d: step %d: Snapshot = %v, model %v", mode, i, got, model)
+			}
+		}
+	}
+}
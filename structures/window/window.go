@@ -0,0 +1,286 @@
+// Package window computes rolling statistics over a sliding window of
+// samples held in a ring buffer from structures/ringbuf.
+//
+// A window is bounded by a number of samples, by age, or both. Sum, Mean,
+// Min and Max are answered in O(1) and updates are amortized O(1); Min and
+// Max are tracked with monotonic queues next to the ring. Percentile sorts
+// a copy of the window and is O(n log n).
+//
+// The running sum is kept with compensated (Kahan-Babuška) summation, so
+// subtracting evicted samples does not accumulate rounding error even when
+// large and small samples mix.
+//
+// A count-bounded window suits latency dashboards ("p99 of the last 1000
+// requests"); an age-bounded one suits rate limiting, where Len is the
+// number of events in the last period.
+package window
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ops2go/go-fundamentals/structures/ringbuf"
+)
+
+var (
+	// ErrMaxAge is returned by NewTimed for an age limit that is not positive.
+	ErrMaxAge = errors.New("window: maxAge must be positive")
+	// ErrNotFinite is returned by Add for a NaN or infinite sample. NaN has
+	// no place in the ordering Min, Max and Percentile rely on, and an
+	// infinity cannot be subtracted from the sum once it is evicted.
+	ErrNotFinite = errors.New("window: sample is NaN or infinite")
+)
+
+// A Window holds the most recent samples and reports statistics over them.
+// It is safe for concurrent use.
+type Window struct {
+	mu     sync.Mutex
+	ring   *ringbuf.Ring[sample]
+	maxAge time.Duration
+	now    func() time.Time
+	seq    uint64
+	sum    float64
+	comp   float64 // compensation for the low-order bits lost from sum
+	mins   deque   // increasing values; the front is the minimum
+	maxs   deque   // decreasing values; the front is the maximum
+}
+
+type sample struct {
+	seq uint64
+	at  time.Time
+	v   float64
+}
+
+// New returns a window over the last size samples.
+// It panics if size is not positive.
+func New(size int) *Window {
+	return &Window{ring: ringbuf.New[sample](size, ringbuf.Error)}
+}
+
+// NewTimed returns a window over the samples added in the last maxAge, of
+// which it keeps at most size. now returns the current time; nil means
+// time.Now. It returns ErrMaxAge if maxAge is not positive, and panics if
+// size is not positive.
+func NewTimed(maxAge time.Duration, size int, now func() time.Time) (*Window, error) {
+	if maxAge <= 0 {
+		return nil, ErrMaxAge
+	}
+	if now == nil {
+		now = time.Now
+	}
+	w := New(size)
+	w.maxAge = maxAge
+	w.now = now
+	return w, nil
+}
+
+// Add records the sample v, evicting the oldest sample if the window is full.
+// It returns ErrNotFinite, and records nothing, if v is NaN or infinite.
+func (w *Window) Add(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ErrNotFinite
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var at time.Time
+	if w.now != nil {
+		at = w.now()
+		w.expire(at)
+	}
+	if w.ring.Len() == w.ring.Cap() {
+		w.evict()
+	}
+
+	w.seq++
+	s := sample{seq: w.seq, at: at, v: v}
+	w.ring.Push(s)
+	w.add(v)
+	w.mins.pushMonotonic(s, func(back float64) bool { return back >= v })
+	w.maxs.pushMonotonic(s, func(back float64) bool { return back <= v })
+	return nil
+}
+
+// Len returns the number of samples in the window.
+func (w *Window) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refresh()
+	return w.ring.Len()
+}
+
+// Sum returns the sum of the samples in the window.
+func (w *Window) Sum() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refresh()
+	return w.sum + w.comp
+}
+
+// Mean returns the mean of the samples in the window, or NaN if it is empty.
+func (w *Window) Mean() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refresh()
+	if w.ring.Len() == 0 {
+		return math.NaN()
+	}
+	return (w.sum + w.comp) / float64(w.ring.Len())
+}
+
+// Min returns the smallest sample in the window, or NaN if it is empty.
+func (w *Window) Min() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refresh()
+	return w.mins.front()
+}
+
+// Max returns the largest sample in the window, or NaN if it is empty.
+func (w *Window) Max() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.refresh()
+	return w.maxs.front()
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the samples in
+// the window using the nearest-rank method, or NaN if it is empty.
+func (w *Window) Percentile(p float64) float64 {
+	w.mu.Lock()
+	samples := w.snapshot()
+	w.mu.Unlock()
+	if len(samples) == 0 {
+		return math.NaN()
+	}
+
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = s.v
+	}
+	slices.Sort(values)
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	return values[min(max(rank, 1), len(values))-1]
+}
+
+// Values returns the samples in the window, oldest first.
+func (w *Window) Values() []float64 {
+	w.mu.Lock()
+	samples := w.snapshot()
+	w.mu.Unlock()
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = s.v
+	}
+	return values
+}
+
+// Reset empties the window.
+func (w *Window) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ring.Reset()
+	w.sum, w.comp = 0, 0
+	w.mins.reset()
+	w.maxs.reset()
+}
+
+// snapshot expires old samples and returns the rest. w.mu must be held.
+func (w *Window) snapshot() []sample {
+	w.refresh()
+	return w.ring.Snapshot()
+}
+
+// refresh expires samples that have aged out of a timed window.
+// w.mu must be held.
+func (w *Window) refresh() {
+	if w.now != nil {
+		w.expire(w.now())
+	}
+}
+
+// expire evicts samples added at or before now minus the window's maximum
+// age. w.mu must be held.
+func (w *Window) expire(now time.Time) {
+	cutoff := now.Add(-w.maxAge)
+	for {
+		s, ok := w.ring.Peek()
+		if !ok || s.at.After(cutoff) {
+			return
+		}
+		w.evict()
+	}
+}
+
+// evict removes the oldest sample. w.mu must be held.
+func (w *Window) evict() {
+	s, ok := w.ring.Pop()
+	if !ok {
+		return
+	}
+	if w.ring.Len() == 0 {
+		// Start afresh rather than carry rounding error forward.
+		w.sum, w.comp = 0, 0
+	} else {
+		w.add(-s.v)
+	}
+	w.mins.popIf(s.seq)
+	w.maxs.popIf(s.seq)
+}
+
+// add adds v to the running sum using Neumaier's variant of Kahan
+// summation. w.mu must be held.
+func (w *Window) add(v float64) {
+	t := w.sum + v
+	if math.Abs(w.sum) >= math.Abs(v) {
+		w.comp += (w.sum - t) + v
+	} else {
+		w.comp += (v - t) + w.sum
+	}
+	w.sum = t
+}
+
+// A deque is a monotonic queue of samples. Samples leave from the front
+// when they leave the window and from the back when a newer sample makes
+// them irrelevant.
+type deque struct {
+	s    []sample
+	head int
+}
+
+// pushMonotonic drops samples from the back while drop reports true for
+// their values, then appends s.
+func (d *deque) pushMonotonic(s sample, drop func(back float64) bool) {
+	for len(d.s) > d.head && drop(d.s[len(d.s)-1].v) {
+		d.s = d.s[:len(d.s)-1]
+	}
+	d.s = append(d.s, s)
+}
+
+// popIf removes the front sample if it has sequence number seq.
+func (d *deque) popIf(seq uint64) {
+	if d.head < len(d.s) && d.s[d.head].seq == seq {
+		d.head++
+		if d.head == len(d.s) {
+			d.reset()
+		} else if d.head > len(d.s)/2 {
+			// Compact so the backing array does not grow without bound.
+			d.s = d.s[:copy(d.s, d.s[d.head:])]
+			d.head = 0
+		}
+	}
+}
+
+func (d *deque) front() float64 {
+	if d.head == len(d.s) {
+		return math.NaN()
+	}
+	return d.s[d.head].v
+}
+
+func (d *deque) reset() {
+	d.s = d.s[:0]
+	d.head = 0
+}
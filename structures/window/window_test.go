@@ -0,0 +1,105 @@
+package window
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewTimedRejectsMaxAge(t *testing.T) {
+	for _, age := range []time.Duration{0, -time.Second} {
+		if w, err := NewTimed(age, 10, nil); err != ErrMaxAge || w != nil {
+			t.Errorf("NewTimed(%v) = %v, %v, want nil, ErrMaxAge", age, w, err)
+		}
+	}
+}
+
+func TestAddRejectsNotFinite(t *testing.T) {
+	w := New(3)
+	w.Add(2)
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if err := w.Add(v); err != ErrNotFinite {
+			t.Fatalf("Add(%v) = %v, want ErrNotFinite", v, err)
+		}
+	}
+	w.Add(1)
+	w.Add(3)
+	if w.Len() != 3 || w.Min() != 1 || w.Max() != 3 || w.Sum() != 6 {
+		t.Fatalf("after rejects: Len %d Min %v Max %v Sum %v", w.Len(), w.Min(), w.Max(), w.Sum())
+	}
+}
+
+func TestSumAfterEvictingLargeSample(t *testing.T) {
+	// Plain summation loses the 1 added next to 1e100 and reports 1.
+	w := New(2)
+	for _, v := range []float64{1e100, 1, 1} {
+		w.Add(v)
+	}
+	if got := w.Sum(); got != 2 {
+		t.Fatalf("Sum = %v, want 2", got)
+	}
+	if got := w.Mean(); got != 1 {
+		t.Fatalf("Mean = %v, want 1", got)
+	}
+}
+
+func TestWindowRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	now := time.Unix(0, 0)
+	w, err := NewTimed(10*time.Second, 50, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	type ref struct {
+		at time.Time
+		v  float64
+	}
+	var want []ref
+	for range 5000 {
+		now = now.Add(time.Duration(r.IntN(500)) * time.Millisecond)
+		// Mix magnitudes so that a drifting sum would show.
+		v := r.Float64() * math.Pow(10, float64(r.IntN(16)-4))
+		if err := w.Add(v); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, ref{now, v})
+		want = slices.DeleteFunc(want, func(s ref) bool { return !s.at.After(now.Add(-10 * time.Second)) })
+		if len(want) > 50 {
+			want = want[len(want)-50:]
+		}
+
+		var values []float64
+		for _, s := range want {
+			values = append(values, s.v)
+		}
+		if got := w.Values(); !slices.Equal(got, values) {
+			t.Fatalf("Values = %v, want %v", got, values)
+		}
+		if w.Min() != slices.Min(values) || w.Max() != slices.Max(values) {
+			t.Fatalf("Min, Max = %v, %v, want %v, %v", w.Min(), w.Max(), slices.Min(values), slices.Max(values))
+		}
+
+		var sum, abs float64
+		for _, v := range values {
+			sum += v
+			abs += math.Abs(v)
+		}
+		// A fresh sum over at most 50 values is within 50 ulps of exact.
+		tol := 1e-13 * abs
+		if got := w.Sum(); math.Abs(got-sum) > tol {
+			t.Fatalf("Sum = %v, want %v", got, sum)
+		}
+		if got, mean := w.Mean(), sum/float64(len(values)); math.Abs(got-mean) > tol/float64(len(values)) {
+			t.Fatalf("Mean = %v, want %v", got, mean)
+		}
+		sorted := slices.Sorted(slices.Values(values))
+		for _, p := range []float64{0, 25, 50, 90, 99, 100} {
+			rank := max(int(math.Ceil(p/100*float64(len(sorted)))), 1)
+			if got := w.Percentile(p); got != sorted[rank-1] {
+				t.Fatalf("Percentile(%v) = %v, want %v", p, got, sorted[rank-1])
+			}
+		}
+	}
+}